@@ -0,0 +1,162 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mitchellh/cli"
+)
+
+// tagCopier copies tags from one file onto others.
+type tagCopier struct {
+	// keys, if not nil, are the only ones copied.
+	keys []string
+	// exclude are never copied.
+	exclude []string
+}
+
+func (c *tagCopier) selected(key, file string) bool {
+	key = canonicalKey(key, file)
+	for _, k := range c.exclude {
+		if strings.EqualFold(canonicalKey(k, file), key) {
+			return false
+		}
+	}
+	if c.keys == nil {
+		return true
+	}
+	for _, k := range c.keys {
+		if strings.EqualFold(canonicalKey(k, file), key) {
+			return true
+		}
+	}
+	return false
+}
+
+// actions returns tagutil actions making dst tags of dstFile
+// (in selected keys) equal to src tags of srcFile.
+func (c *tagCopier) actions(src Tags, srcFile string, dst Tags, dstFile string) []string {
+	var ops []*setOp
+	for _, key := range src.Keys() {
+		if !c.selected(key, srcFile) {
+			continue
+		}
+		dstKey := formatKey(key, srcFile, dstFile)
+		for _, value := range src.Values(key) {
+			ops = append(ops, &setOp{mode: setReplace, key: dstKey, value: value})
+		}
+	}
+	return applySets(dst, ops)
+}
+
+// copy applies tags of srcFile to dstFile.
+func (c *tagCopier) copy(src Tags, srcFile, dstFile string) error {
+	dst, err := readTags(dstFile)
+	if err != nil {
+		return err
+	}
+	actions := c.actions(src, srcFile, dst, dstFile)
+	if len(actions) == 0 {
+		return nil
+	}
+	return writeTags(dstFile, actions)
+}
+
+// parseKeys reads -t and -e flags (comma separated lists) into c.
+func (c *tagCopier) parseKeys(tags, exclude string) {
+	if tags != "" {
+		c.keys = strings.Split(tags, ",")
+	}
+	if exclude != "" {
+		c.exclude = strings.Split(exclude, ",")
+	}
+}
+
+type CopyCommand struct {
+	ui     cli.Ui
+	wg     sync.WaitGroup
+	copier *tagCopier
+	where  *Query
+}
+
+func (cmd *CopyCommand) Process(src Tags, srcFile, file string) {
+	defer cmd.wg.Done()
+	cmd.ui.Output(fmt.Sprintf("Processing `%s`", file))
+
+	if err := cmd.copier.copy(src, srcFile, file); err != nil {
+		cmd.ui.Error(fmt.Sprintf("`%s`: %s", file, err))
+	}
+}
+
+func (cmd *CopyCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("copy", flag.ContinueOnError)
+	flags.Usage = func() { cmd.ui.Output(cmd.Help()) }
+	tagsFlag := flags.String("t", "", "")
+	excludeFlag := flags.String("e", "", "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	args = flags.Args()
+	if len(args) < 3 || args[1] != "--" {
+		cmd.ui.Output(cmd.Help())
+		return 1
+	}
+
+	cmd.copier = &tagCopier{}
+	cmd.copier.parseKeys(*tagsFlag, *excludeFlag)
+
+	srcFile := args[0]
+	src, err := readTags(srcFile)
+	if err != nil {
+		cmd.ui.Error(fmt.Sprintf("`%s`: %s", srcFile, err))
+		return 1
+	}
+
+	for _, file := range cmd.where.Filter(cmd.ui, args[2:]) {
+		cmd.wg.Add(1)
+		go cmd.Process(src, srcFile, file)
+	}
+
+	cmd.wg.Wait()
+	return 0
+}
+
+func (cmd *CopyCommand) Help() string {
+	return strings.TrimSpace(`
+usage: tu cp [-t TAGS] [-e TAGS] SRC -- FILES...
+
+Copies tags (with all their values) from SRC onto FILES.
+Tag names are mapped between formats, e.g. DATE in FLAC is YEAR in MP3.
+
+-t TAGS	Comma separated list of tag names to copy.
+	If not specified, copies everything.
+-e TAGS	Comma separated list of tag names not to copy.
+	`)
+}
+
+func (cmd *CopyCommand) Synopsis() string {
+	return "Copies tags from one file to others"
+}
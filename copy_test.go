@@ -0,0 +1,64 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var copyTestSrc = Tags{
+	{"ALBUM", "Kind of Blue"}, {"DATE", "1959"}, {"TITLE", "So What"},
+	{"GENRE", "Jazz"}, {"GENRE", "Modal"},
+}
+
+var TagCopierTests = []struct {
+	copier   *tagCopier
+	dst      Tags
+	dstFile  string
+	expected []string
+}{
+	{&tagCopier{}, Tags{{"TITLE", "So What"}}, "b.flac", []string{
+		"set:ALBUM=Kind of Blue", "set:DATE=1959",
+		"set:GENRE=Jazz", "add:GENRE=Modal",
+	}},
+	{&tagCopier{keys: []string{"album", "genre"}}, Tags{{"GENRE", "Jazz"}}, "b.flac", []string{
+		"set:ALBUM=Kind of Blue", "set:GENRE=Jazz", "add:GENRE=Modal",
+	}},
+	{&tagCopier{exclude: []string{"title", "genre"}}, nil, "b.flac", []string{
+		"set:ALBUM=Kind of Blue", "set:DATE=1959",
+	}},
+	{&tagCopier{keys: []string{"date"}}, Tags{{"year", "1960"}}, "b.mp3", []string{
+		"set:year=1959",
+	}},
+	{&tagCopier{keys: []string{"date"}}, Tags{{"DATE", "1959"}}, "b.flac", nil},
+}
+
+func TestTagCopier_Actions(t *testing.T) {
+	for i, tt := range TagCopierTests {
+		actual := tt.copier.actions(copyTestSrc, "a.flac", tt.dst, tt.dstFile)
+
+		assert.Equal(t, tt.expected, actual, fmt.Sprintf("%d", i))
+	}
+}
@@ -0,0 +1,334 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/mitchellh/cli"
+)
+
+// blockHeader starts a block of tags of a single file.
+const blockHeader = "== "
+
+var blockEscaper = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r")
+
+// unescapeBlock reverts escaping of backslashes and line breaks done by
+// writeBlocks. Unknown sequences are kept as they are.
+func unescapeBlock(value string) string {
+	var out bytes.Buffer
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			out.WriteByte(value[i])
+			continue
+		}
+		switch value[i+1] {
+		case '\\':
+			out.WriteByte('\\')
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		default:
+			out.WriteByte(value[i])
+			continue
+		}
+		i++
+	}
+	return out.String()
+}
+
+// writeBlocks writes t as one block per file, one KEY=VALUE line per value.
+// Line breaks and backslashes in values are escaped, as \n, \r and \\.
+func writeBlocks(w io.Writer, t *tagTable) {
+	for i, row := range t.rows {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s%s\n", blockHeader, row.path)
+		for _, column := range t.columns {
+			for _, value := range row.values[column] {
+				fmt.Fprintf(w, "%s=%s\n", column, blockEscaper.Replace(value))
+			}
+		}
+	}
+}
+
+// readBlocks reads blocks written by writeBlocks. Every row gets all the
+// columns (and the given ones), so that a removed line removes the value.
+func readBlocks(r io.Reader, columns []string) (*tagTable, error) {
+	t := &tagTable{columns: append([]string{}, columns...)}
+	var row *tableRow
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		switch {
+		case strings.TrimSpace(line) == "":
+		case strings.HasPrefix(line, blockHeader):
+			row = &tableRow{path: line[len(blockHeader):], values: map[string][]string{}}
+			t.rows = append(t.rows, row)
+		case row == nil:
+			return nil, fmt.Errorf("line %d: expected `%s<file>`", n, blockHeader)
+		default:
+			split := strings.SplitN(line, "=", 2)
+			key := strings.TrimSpace(split[0])
+			if len(split) != 2 || key == "" {
+				return nil, fmt.Errorf("line %d: expected `TAG=VALUE`", n)
+			}
+			column := ""
+			for _, c := range t.columns {
+				if strings.EqualFold(c, key) {
+					column = c
+					break
+				}
+			}
+			if column == "" {
+				column = key
+				t.columns = append(t.columns, key)
+			}
+			row.values[column] = append(row.values[column], unescapeBlock(split[1]))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, row := range t.rows {
+		for _, column := range t.columns {
+			if _, ok := row.values[column]; !ok {
+				row.values[column] = nil
+			}
+		}
+	}
+	return t, nil
+}
+
+// stripHeader removes the leading lines starting with `#`, which are
+// put on top of the buffer to report an error. Lines further down are
+// kept, since in a table they may belong to a multi-line value.
+func stripHeader(data []byte) []byte {
+	for bytes.HasPrefix(data, []byte("#")) {
+		i := bytes.IndexByte(data, '\n')
+		if i == -1 {
+			return nil
+		}
+		data = data[i+1:]
+	}
+	return data
+}
+
+// checkRows makes sure t has a single row for every file and nothing else.
+func checkRows(t *tagTable, files []string) error {
+	seen := map[string]bool{}
+	for _, row := range t.rows {
+		if !contains(files, row.path) {
+			return fmt.Errorf("unknown file `%s`", row.path)
+		}
+		if seen[row.path] {
+			return fmt.Errorf("file `%s` given more than once", row.path)
+		}
+		seen[row.path] = true
+	}
+	for _, file := range files {
+		if !seen[file] {
+			return fmt.Errorf("file `%s` is missing", file)
+		}
+	}
+	return nil
+}
+
+type EditCommand struct {
+	ui     cli.Ui
+	wg     sync.WaitGroup
+	blocks bool
+	where  *Query
+}
+
+// tagutilEdit edits every file separately, using YAML documents of tagutil.
+func (cmd *EditCommand) tagutilEdit(files []string) int {
+	tagutil := exec.Command("tagutil", prepend(files, "edit")...)
+	tagutil.Stdin = os.Stdin
+	tagutil.Stdout = os.Stdout
+	tagutil.Stderr = os.Stderr
+	if err := tagutil.Run(); err != nil {
+		cmd.ui.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+// editor opens buffer in $EDITOR and returns its edited contents.
+func (cmd *EditCommand) editor(buffer []byte) ([]byte, error) {
+	f, err := ioutil.TempFile("", "tu")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(buffer)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+	run := exec.Command(editor[0], append(editor[1:], f.Name())...)
+	run.Stdin = os.Stdin
+	run.Stdout = os.Stdout
+	run.Stderr = os.Stderr
+	if err := run.Run(); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(f.Name())
+}
+
+func (cmd *EditCommand) parse(data []byte, columns, files []string) (*tagTable, error) {
+	var t *tagTable
+	var err error
+	if cmd.blocks {
+		t, err = readBlocks(bytes.NewReader(data), columns)
+	} else {
+		t, err = readTagTable(bytes.NewReader(data), "tsv", "; ")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, checkRows(t, files)
+}
+
+func (cmd *EditCommand) Process(row *tableRow, tags Tags, columns []string) {
+	defer cmd.wg.Done()
+
+	actions := row.actions(tags, columns)
+	if len(actions) == 0 {
+		return
+	}
+	cmd.ui.Output(fmt.Sprintf("Processing `%s`", row.path))
+	if err := writeTags(row.path, actions); err != nil {
+		cmd.ui.Error(fmt.Sprintf("`%s`: %s", row.path, err))
+	}
+}
+
+func (cmd *EditCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("edit", flag.ContinueOnError)
+	flags.Usage = func() { cmd.ui.Output(cmd.Help()) }
+	flags.BoolVar(&cmd.blocks, "b", false, "")
+	separate := flags.Bool("y", false, "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	files := flags.Args()
+	if len(files) < 1 {
+		cmd.ui.Output(cmd.Help())
+		return 1
+	}
+	if files = cmd.where.Filter(cmd.ui, files); len(files) == 0 {
+		return 0
+	}
+	if *separate || (len(files) == 1 && !cmd.blocks) {
+		return cmd.tagutilEdit(files)
+	}
+
+	tags := map[string]Tags{}
+	all := make([]Tags, len(files))
+	for i, file := range files {
+		t, err := readTags(file)
+		if err != nil {
+			cmd.ui.Error(fmt.Sprintf("`%s`: %s", file, err))
+			return 1
+		}
+		tags[file], all[i] = t, t
+	}
+	original := newTagTable(files, all)
+
+	var buffer bytes.Buffer
+	if cmd.blocks {
+		writeBlocks(&buffer, original)
+	} else if err := original.Write(&buffer, "tsv", "; "); err != nil {
+		cmd.ui.Error(err.Error())
+		return 1
+	}
+
+	data := buffer.Bytes()
+	var edited *tagTable
+	for {
+		out, err := cmd.editor(data)
+		if err != nil {
+			cmd.ui.Error(err.Error())
+			return 1
+		}
+		out = stripHeader(out)
+		if len(bytes.TrimSpace(out)) == 0 {
+			cmd.ui.Output("Empty buffer, nothing was changed")
+			return 1
+		}
+		edited, err = cmd.parse(out, original.columns, files)
+		if err == nil {
+			break
+		}
+		data = append([]byte(fmt.Sprintf(
+			"# Error: %s\n# Fix it, or empty the buffer to cancel.\n", err,
+		)), out...)
+	}
+
+	for _, row := range edited.rows {
+		cmd.wg.Add(1)
+		go cmd.Process(row, tags[row.path], edited.columns)
+	}
+
+	cmd.wg.Wait()
+	return 0
+}
+
+func (cmd *EditCommand) Help() string {
+	return strings.TrimSpace(`
+usage: tu e [-b | -y] FILES...
+
+Edits tags of FILES in $EDITOR, as a single table with one row per file
+	and one column per tag (tab separated, multiple values joined
+	with "; "). Only changed files are written. If the result cannot
+	be parsed, the editor is opened again, with the error on top.
+
+-b Use one block per file, starting with "== <file>" line,
+	followed by TAG=VALUE lines (repeated for multiple values).
+	Line breaks and backslashes in values are written as \n and \\.
+-y Edit each file separately, as YAML (default for a single file).
+	`)
+}
+
+func (cmd *EditCommand) Synopsis() string {
+	return "Edits tags interactively using $EDITOR"
+}
@@ -0,0 +1,141 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteBlocks(t *testing.T) {
+	var out bytes.Buffer
+
+	writeBlocks(&out, testTable())
+
+	assert.Equal(t, strings.Join([]string{
+		"== 01.flac",
+		"TITLE=So What",
+		"GENRE=Jazz",
+		"GENRE=Modal",
+		"",
+		"== 02.flac",
+		"TITLE=Freddie, \"Freeloader\"",
+		"DATE=1959",
+		"",
+	}, "\n"), out.String())
+}
+
+func TestReadBlocks(t *testing.T) {
+	in := strings.Join([]string{
+		"== 01.flac",
+		"TITLE=So What",
+		"genre=Jazz",
+		"MOOD=Cool=Calm",
+		"",
+		"== 02.flac",
+		"DATE=1959",
+	}, "\n")
+
+	table, err := readBlocks(strings.NewReader(in), []string{"TITLE", "GENRE", "DATE"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"TITLE", "GENRE", "DATE", "MOOD"}, table.columns)
+	assert.Equal(t, []string{"Jazz"}, table.rows[0].values["GENRE"])
+	assert.Equal(t, []string{"Cool=Calm"}, table.rows[0].values["MOOD"])
+	assert.Nil(t, table.rows[1].values["TITLE"])
+	_, ok := table.rows[1].values["TITLE"]
+	assert.True(t, ok)
+}
+
+func TestReadBlocks_Errors(t *testing.T) {
+	_, err := readBlocks(strings.NewReader("TITLE=So What\n"), nil)
+	assert.Error(t, err)
+
+	_, err = readBlocks(strings.NewReader("== 01.flac\nSo What\n"), nil)
+	assert.Error(t, err)
+
+	_, err = readBlocks(strings.NewReader("== 01.flac\n=So What\n"), nil)
+	assert.Error(t, err)
+}
+
+func TestStripHeader(t *testing.T) {
+	actual := stripHeader([]byte("# Error: x\n# Fix it\n== 01.flac\n#\nTITLE=#1\n"))
+
+	assert.Equal(t, "== 01.flac\n#\nTITLE=#1\n", string(actual))
+	assert.Empty(t, stripHeader([]byte("# Error: x")))
+}
+
+func TestCheckRows(t *testing.T) {
+	files := []string{"01.flac", "02.flac"}
+
+	assert.NoError(t, checkRows(testTable(), files))
+	assert.Error(t, checkRows(testTable(), files[:1]))
+	assert.Error(t, checkRows(testTable(), append(files, "03.flac")))
+
+	table := testTable()
+	table.rows[1].path = "01.flac"
+	assert.Error(t, checkRows(table, files))
+}
+
+func TestBlocks_RoundTrip(t *testing.T) {
+	tags := []Tags{
+		{{"LYRICS", "line one\nkey=value\r\n== 02.flac"}, {"COMMENT", `C:\new\\dir`}},
+		{{"TITLE", "So What"}},
+	}
+	table := newTagTable([]string{"01.flac", "02.flac"}, tags)
+	var out bytes.Buffer
+	writeBlocks(&out, table)
+
+	actual, err := readBlocks(&out, table.columns)
+
+	assert.NoError(t, err)
+	assert.Len(t, actual.rows, 2)
+	for i, row := range actual.rows {
+		assert.Empty(t, row.actions(tags[i], actual.columns))
+	}
+	assert.Equal(t, []string{"line one\nkey=value\r\n== 02.flac"}, actual.rows[0].values["LYRICS"])
+}
+
+func TestUnescapeBlock(t *testing.T) {
+	assert.Equal(t, "a\nb\\c\\x\\", unescapeBlock(`a\nb\\c\x\`))
+}
+
+func TestEditCommand_ParseUnedited(t *testing.T) {
+	tags := []Tags{
+		{{"COMMENT", "Recorded live; remastered"}, {"GENRE", "Jazz"}, {"GENRE", "Modal"}},
+		{{"LYRICS", "line one\n#chorus\nline two"}},
+	}
+	files := []string{"01.flac", "02.flac"}
+	table := newTagTable(files, tags)
+	var out bytes.Buffer
+	assert.NoError(t, table.Write(&out, "tsv", "; "))
+
+	actual, err := (&EditCommand{}).parse(stripHeader(out.Bytes()), table.columns, files)
+
+	assert.NoError(t, err)
+	for i, row := range actual.rows {
+		assert.Empty(t, row.actions(tags[i], actual.columns))
+	}
+}
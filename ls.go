@@ -0,0 +1,258 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/mitchellh/cli"
+)
+
+// listRow is a single listed file, with its tags and file properties.
+type listRow struct {
+	path  string
+	props Tags
+}
+
+// compareValues compares tag values, numerically if both start
+// with a number, so that track 10 goes after track 9.
+func compareValues(a, b string) int {
+	na, erra := strconv.Atoi(strings.TrimSpace(strings.SplitN(a, "/", 2)[0]))
+	nb, errb := strconv.Atoi(strings.TrimSpace(strings.SplitN(b, "/", 2)[0]))
+	if erra == nil && errb == nil {
+		switch {
+		case na < nb:
+			return -1
+		case na > nb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// lessValues orders tag values by compareValues, falling back
+// to plain text for numerically equal ones.
+func lessValues(a, b string) bool {
+	if c := compareValues(a, b); c != 0 {
+		return c < 0
+	}
+	return strings.ToLower(a) < strings.ToLower(b)
+}
+
+func sortRows(rows []*listRow, key string) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return lessValues(rows[i].props.Get(key), rows[j].props.Get(key))
+	})
+}
+
+// listColumns returns _path followed by all tags found in rows.
+func listColumns(rows []*listRow) []string {
+	columns := []string{pathColumn}
+	for _, row := range rows {
+		for _, key := range row.props.Keys() {
+			if strings.HasPrefix(key, "_") {
+				continue
+			}
+			found := false
+			for _, column := range columns {
+				found = found || strings.EqualFold(column, key)
+			}
+			if !found {
+				columns = append(columns, key)
+			}
+		}
+	}
+	return columns
+}
+
+// listTable renders rows as an aligned table, with a header.
+func listTable(rows []*listRow, columns []string) string {
+	var out bytes.Buffer
+	w := tabwriter.NewWriter(&out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(columns, "\t"))
+	for _, row := range rows {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = strings.Join(row.props.Values(column), "; ")
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	w.Flush()
+	return strings.TrimRight(out.String(), "\n")
+}
+
+// listTemplate renders every row with template, one per line.
+func listTemplate(rows []*listRow, template *Template) string {
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		lines[i] = template.Render(row.props, nil)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// listJSON renders rows the same way tu x does.
+func listJSON(rows []*listRow, columns []string) (string, error) {
+	t := &tagTable{}
+	for _, column := range columns {
+		if column != pathColumn {
+			t.columns = append(t.columns, column)
+		}
+	}
+	for _, row := range rows {
+		values := map[string][]string{}
+		for _, column := range t.columns {
+			values[column] = row.props.Values(column)
+		}
+		t.rows = append(t.rows, &tableRow{path: row.path, values: values})
+	}
+	var out bytes.Buffer
+	err := t.Write(&out, "json", "")
+	return strings.TrimRight(out.String(), "\n"), err
+}
+
+type ListCommand struct {
+	ui    cli.Ui
+	where *Query
+}
+
+func (cmd *ListCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	flags.Usage = func() { cmd.ui.Output(cmd.Help()) }
+	columnsFlag := flags.String("c", "", "")
+	formatFlag := flags.String("f", "", "")
+	asJSON := flags.Bool("j", false, "")
+	sortKey := flags.String("s", "", "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	files := flags.Args()
+	if len(files) < 1 {
+		cmd.ui.Output(cmd.Help())
+		return 1
+	}
+	files = cmd.where.Filter(cmd.ui, files)
+
+	var columns []string
+	if *columnsFlag != "" {
+		columns = strings.Split(*columnsFlag, ",")
+	}
+	var template *Template
+	names := append(append([]string{}, columns...), *sortKey)
+	if *formatFlag != "" {
+		var err error
+		if template, err = ParseTemplate(*formatFlag); err != nil {
+			cmd.ui.Error(err.Error())
+			return 1
+		}
+		names = append(names, template.Names()...)
+	}
+	audio := false
+	for _, name := range names {
+		audio = audio || contains(audioProps, strings.ToLower(name))
+	}
+
+	rows := make([]*listRow, len(files))
+	errs := make([]error, len(files))
+	var wg sync.WaitGroup
+	for i, file := range files {
+		wg.Add(1)
+		go func(i int, file string) {
+			defer wg.Done()
+			tags, err := readTags(file)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			props := append(tags, fileProps(file)...)
+			if audio {
+				more, err := readAudioProps(file)
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				props = append(props, more...)
+			}
+			rows[i] = &listRow{path: file, props: props}
+		}(i, file)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			cmd.ui.Error(fmt.Sprintf("`%s`: %s", files[i], err))
+			return 1
+		}
+	}
+
+	if *sortKey != "" {
+		sortRows(rows, *sortKey)
+	}
+	if columns == nil {
+		columns = listColumns(rows)
+	}
+
+	switch {
+	case template != nil:
+		cmd.ui.Output(listTemplate(rows, template))
+	case *asJSON:
+		out, err := listJSON(rows, columns)
+		if err != nil {
+			cmd.ui.Error(err.Error())
+			return 1
+		}
+		cmd.ui.Output(out)
+	default:
+		cmd.ui.Output(listTable(rows, columns))
+	}
+	return 0
+}
+
+func (cmd *ListCommand) Help() string {
+	return strings.TrimSpace(`
+usage: tu ls [-c COLUMNS | -f FORMAT | -j] [-s KEY] FILES...
+
+Prints tags of FILES as an aligned table, one row per file.
+Multiple values of a tag are joined with "; ".
+
+-c COLUMNS Comma separated list of columns to print.
+	If not specified, prints _path followed by all the tags.
+-f FORMAT Print a line per file, filled in from FORMAT
+	instead, e.g. '%tracknumber. %title'.
+-j Print JSON, the same as tu x does.
+-s KEY Sort files by KEY, numerically when possible.
+
+Besides tags, COLUMNS, FORMAT and KEY can use the same file and audio
+	properties as tu s, e.g. _filename or _length.
+FORMAT has the same syntax as PATTERN of tu r.
+	`)
+}
+
+func (cmd *ListCommand) Synopsis() string {
+	return "Lists tags of files"
+}
@@ -0,0 +1,97 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testRows() []*listRow {
+	return []*listRow{
+		{"10.flac", append(Tags{{"TRACKNUMBER", "10"}, {"TITLE", "Flamenco Sketches"}}, fileProps("10.flac")...)},
+		{"9.flac", append(Tags{
+			{"TRACKNUMBER", "9/10"}, {"TITLE", "All Blues"}, {"GENRE", "Jazz"}, {"GENRE", "Modal"},
+		}, fileProps("9.flac")...)},
+	}
+}
+
+var LessValuesTests = []struct {
+	a, b     string
+	expected bool
+}{
+	{"9", "10", true},
+	{"10", "9/10", false},
+	{"02", "2", true},
+	{"abba", "Beatles", true},
+	{"A1", "B1", true},
+	{"", "1", true},
+}
+
+func TestLessValues(t *testing.T) {
+	for i, tt := range LessValuesTests {
+		actual := lessValues(tt.a, tt.b)
+
+		assert.Equal(t, tt.expected, actual, fmt.Sprintf("%d", i))
+	}
+}
+
+func TestSortRows(t *testing.T) {
+	rows := testRows()
+
+	sortRows(rows, "tracknumber")
+
+	assert.Equal(t, "9.flac", rows[0].path)
+	assert.Equal(t, "10.flac", rows[1].path)
+}
+
+func TestListColumns(t *testing.T) {
+	assert.Equal(t, []string{"_path", "TRACKNUMBER", "TITLE", "GENRE"}, listColumns(testRows()))
+}
+
+func TestListTable(t *testing.T) {
+	actual := listTable(testRows(), []string{"_path", "title", "genre"})
+
+	assert.Equal(t, strings.Join([]string{
+		"_path    title              genre",
+		"10.flac  Flamenco Sketches",
+		"9.flac   All Blues          Jazz; Modal",
+	}, "\n"), strings.Replace(actual, "  \n", "\n", -1))
+}
+
+func TestListTemplate(t *testing.T) {
+	template, _ := ParseTemplate("%{tracknumber:02}. %title (%{_filename})")
+
+	actual := listTemplate(testRows(), template)
+
+	assert.Equal(t, "10. Flamenco Sketches (10)\n09. All Blues (9)", actual)
+}
+
+func TestListJSON(t *testing.T) {
+	actual, err := listJSON(testRows()[1:], []string{"_path", "GENRE"})
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"_path": "9.flac", "GENRE": ["Jazz", "Modal"]}]`, actual)
+}
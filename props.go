@@ -0,0 +1,111 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// audioProps are pseudo tags read from the audio stream,
+// which requires running ffprobe.
+var audioProps = []string{
+	"_duration", "_length", "_bitrate", "_samplerate", "_channels", "_codec",
+}
+
+// fileProps returns pseudo tags describing the file itself, rather
+// than its contents. Their names start with `_`, not to clash with tags.
+func fileProps(file string) Tags {
+	base := filepath.Base(file)
+	ext := filepath.Ext(base)
+	dir := filepath.Dir(file)
+	if abs, err := filepath.Abs(file); err == nil {
+		dir = filepath.Dir(abs)
+	}
+	return Tags{
+		{"_path", file},
+		{"_dirname", filepath.Base(dir)},
+		{"_filename", strings.TrimSuffix(base, ext)},
+		{"_ext", strings.TrimPrefix(ext, ".")},
+	}
+}
+
+type probeOutput struct {
+	Streams []struct {
+		CodecName  string `json:"codec_name"`
+		SampleRate string `json:"sample_rate"`
+		Channels   int    `json:"channels"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+		BitRate  string `json:"bit_rate"`
+	} `json:"format"`
+}
+
+// parseProbe turns ffprobe's JSON output into audio pseudo tags.
+// Duration is in whole seconds, length is M:SS and bitrate is in kbps.
+func parseProbe(out []byte) (Tags, error) {
+	var probe probeOutput
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return nil, err
+	}
+	if len(probe.Streams) == 0 {
+		return nil, fmt.Errorf("no audio stream found")
+	}
+	stream := probe.Streams[0]
+
+	props := Tags{
+		{"_samplerate", stream.SampleRate},
+		{"_channels", strconv.Itoa(stream.Channels)},
+		{"_codec", stream.CodecName},
+	}
+	if duration, err := strconv.ParseFloat(probe.Format.Duration, 64); err == nil {
+		seconds := int(math.Floor(duration + 0.5))
+		props = append(props,
+			Tag{"_duration", strconv.Itoa(seconds)},
+			Tag{"_length", fmt.Sprintf("%d:%02d", seconds/60, seconds%60)},
+		)
+	}
+	if bitrate, err := strconv.Atoi(probe.Format.BitRate); err == nil {
+		props = append(props, Tag{"_bitrate", strconv.Itoa(bitrate / 1000)})
+	}
+	return props, nil
+}
+
+// readAudioProps returns audio pseudo tags of file, see audioProps.
+func readAudioProps(file string) (Tags, error) {
+	ffprobe := exec.Command(
+		"ffprobe", "-v", "error", "-select_streams", "a:0",
+		"-show_entries", "stream=codec_name,sample_rate,channels:format=duration,bit_rate",
+		"-of", "json", file,
+	)
+	out, err := ffprobe.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe: %s", err)
+	}
+	return parseProbe(out)
+}
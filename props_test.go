@@ -0,0 +1,59 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileProps(t *testing.T) {
+	props := fileProps("/music/Kind of Blue/01 - So What.flac")
+
+	assert.Equal(t, "Kind of Blue", props.Get("_dirname"))
+	assert.Equal(t, "01 - So What", props.Get("_filename"))
+	assert.Equal(t, "flac", props.Get("_ext"))
+}
+
+func TestParseProbe(t *testing.T) {
+	out := []byte(`{
+		"streams": [{"codec_name": "flac", "sample_rate": "44100", "channels": 2}],
+		"format": {"duration": "562.493333", "bit_rate": "912345"}
+	}`)
+
+	props, err := parseProbe(out)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "flac", props.Get("_codec"))
+	assert.Equal(t, "44100", props.Get("_samplerate"))
+	assert.Equal(t, "2", props.Get("_channels"))
+	assert.Equal(t, "562", props.Get("_duration"))
+	assert.Equal(t, "9:22", props.Get("_length"))
+	assert.Equal(t, "912", props.Get("_bitrate"))
+}
+
+func TestParseProbe_NoStream(t *testing.T) {
+	_, err := parseProbe([]byte(`{"streams": [], "format": {}}`))
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,572 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/mitchellh/cli"
+)
+
+type renameOp struct {
+	src string
+	dst string
+	err error
+}
+
+// foldPath returns a key under which paths differing only
+// in letter case compare equal.
+func foldPath(file string) string {
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	return strings.ToLower(filepath.Clean(file))
+}
+
+func withSuffix(file string, n int) string {
+	ext := filepath.Ext(file)
+	return fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(file, ext), n, ext)
+}
+
+// dirListing caches case folded directory contents,
+// so that clashes are found on case sensitive filesystems as well.
+type dirListing map[string]map[string]string
+
+func (l dirListing) lookup(file string) (string, bool) {
+	dir := filepath.Dir(file)
+	key := foldPath(dir)
+	names, ok := l[key]
+	if !ok {
+		names = map[string]string{}
+		infos, _ := ioutil.ReadDir(dir)
+		for _, info := range infos {
+			names[strings.ToLower(info.Name())] = info.Name()
+		}
+		l[key] = names
+	}
+	name, ok := names[strings.ToLower(filepath.Base(file))]
+	if !ok {
+		return "", false
+	}
+	return filepath.Join(dir, name), true
+}
+
+var companionExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
+	".cue": true, ".log": true, ".lrc": true,
+}
+
+// renamer holds options controlling how files get to their targets.
+type renamer struct {
+	suffix     bool
+	copy       bool
+	companions bool
+	prune      bool
+}
+
+// addCompanions appends moves for covers, cue sheets, logs and lyrics
+// found next to the files. Files with the same name as a track follow
+// that track, others follow the album, but only when all its tracks
+// end up in the same directory.
+func (r *renamer) addCompanions(ops []*renameOp) []*renameOp {
+	sources := map[string]bool{}
+	dirs := []string{}
+	targets := map[string]map[string]bool{}
+	tracks := map[string]*renameOp{}
+	for _, op := range ops {
+		sources[foldPath(op.src)] = true
+		dir := filepath.Dir(op.src)
+		if _, ok := targets[dir]; !ok {
+			dirs = append(dirs, dir)
+			targets[dir] = map[string]bool{}
+		}
+		targets[dir][filepath.Dir(op.dst)] = true
+		tracks[foldPath(strings.TrimSuffix(op.src, filepath.Ext(op.src)))] = op
+	}
+
+	for _, dir := range dirs {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, info := range infos {
+			src := filepath.Join(dir, info.Name())
+			ext := filepath.Ext(src)
+			if info.IsDir() || sources[foldPath(src)] || !companionExts[strings.ToLower(ext)] {
+				continue
+			}
+
+			if track, ok := tracks[foldPath(strings.TrimSuffix(src, ext))]; ok {
+				dst := strings.TrimSuffix(track.dst, filepath.Ext(track.dst)) + ext
+				ops = append(ops, &renameOp{src: src, dst: dst})
+				continue
+			}
+			if len(targets[dir]) != 1 {
+				continue
+			}
+			for target := range targets[dir] {
+				ops = append(ops, &renameOp{src: src, dst: filepath.Join(target, info.Name())})
+			}
+		}
+	}
+	return ops
+}
+
+// plan checks every target against the other targets in the batch
+// and against files already present on disk, ignoring letter case.
+//
+// If suffix option is set, clashing targets get a " (N)" suffix appended.
+// Otherwise, they are left intact and returned as a list of problems.
+func (r *renamer) plan(ops []*renameOp) (clashes []string) {
+	moving := map[string]bool{}
+	claimed := map[string]string{}
+	for _, op := range ops {
+		if op.src == op.dst {
+			claimed[foldPath(op.dst)] = op.src
+		} else if !r.copy {
+			moving[foldPath(op.src)] = true
+		}
+	}
+
+	listing := dirListing{}
+	clash := func(op *renameOp, dst string) string {
+		if src, ok := claimed[foldPath(dst)]; ok && src != op.src {
+			return fmt.Sprintf("also the target of `%s`", src)
+		}
+		if existing, ok := listing.lookup(dst); ok && !moving[foldPath(existing)] {
+			return fmt.Sprintf("`%s` already exists", existing)
+		}
+		return ""
+	}
+
+	for _, op := range ops {
+		if op.src == op.dst {
+			continue
+		}
+		dst := op.dst
+		for n := 2; ; n++ {
+			reason := clash(op, dst)
+			if reason == "" {
+				break
+			}
+			if !r.suffix {
+				clashes = append(clashes, fmt.Sprintf(
+					"`%s` -> `%s`: %s", op.src, op.dst, reason,
+				))
+				break
+			}
+			dst = withSuffix(op.dst, n)
+		}
+		op.dst = dst
+		claimed[foldPath(dst)] = op.src
+	}
+	return
+}
+
+// apply moves (or copies) the files to their targets, creating
+// directories as needed. When a target is another file's source,
+// everything is first moved aside to a temporary name, so that
+// chains and cycles do not overwrite anything. If a step fails,
+// files still under a temporary name are moved back where possible.
+func (r *renamer) apply(ops []*renameOp) error {
+	sources := map[string]bool{}
+	dirs := []string{}
+	for _, op := range ops {
+		if op.src != op.dst {
+			sources[foldPath(op.src)] = true
+			dirs = append(dirs, filepath.Dir(op.src))
+		}
+	}
+	twoPhase := false
+	for _, op := range ops {
+		if !r.copy && op.src != op.dst && sources[foldPath(op.dst)] {
+			twoPhase = true
+			break
+		}
+	}
+
+	// orig[i] holds the original name of ops[i] while it is moved aside.
+	orig := make([]string, len(ops))
+	if twoPhase {
+		for i, op := range ops {
+			if op.src == op.dst {
+				continue
+			}
+			tmp := filepath.Join(
+				filepath.Dir(op.src),
+				fmt.Sprintf(".tu-%d-%s", i, filepath.Base(op.src)),
+			)
+			if err := os.Rename(op.src, tmp); err != nil {
+				return restoreTemps(ops, orig, err)
+			}
+			orig[i], op.src = op.src, tmp
+		}
+	}
+
+	for i, op := range ops {
+		if op.src == op.dst {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(op.dst), 0755); err != nil {
+			return restoreTemps(ops, orig, err)
+		}
+		var err error
+		if r.copy {
+			err = copyFile(op.src, op.dst)
+		} else {
+			err = moveFile(op.src, op.dst)
+		}
+		if err != nil {
+			return restoreTemps(ops, orig, err)
+		}
+		orig[i] = ""
+	}
+
+	if r.prune && !r.copy {
+		for _, dir := range dirs {
+			pruneDir(dir)
+		}
+	}
+	return nil
+}
+
+// restoreTemps moves files left under a temporary name by a failed apply
+// back to their original names. Those that cannot be moved back, because
+// the name is taken by now or the rename fails, are listed in the error.
+func restoreTemps(ops []*renameOp, orig []string, err error) error {
+	var stranded []string
+	for i, op := range ops {
+		if orig[i] == "" {
+			continue
+		}
+		if _, serr := os.Lstat(orig[i]); serr == nil || os.Rename(op.src, orig[i]) != nil {
+			stranded = append(stranded, fmt.Sprintf("`%s` (was `%s`)", op.src, orig[i]))
+			continue
+		}
+		op.src = orig[i]
+	}
+	if len(stranded) == 0 {
+		return err
+	}
+	return fmt.Errorf("%v; left under a temporary name: %s", err, strings.Join(stranded, ", "))
+}
+
+func hashFile(file string) ([]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// copyFile copies src contents, permissions and modification time to dst,
+// then verifies that both files have the same contents.
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	os.Chtimes(dst, info.ModTime(), info.ModTime())
+
+	srcHash, err := hashFile(src)
+	if err != nil {
+		return err
+	}
+	dstHash, err := hashFile(dst)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(srcHash, dstHash) {
+		os.Remove(dst)
+		return fmt.Errorf("`%s`: copy verification failed", src)
+	}
+	return nil
+}
+
+// moveFile renames src to dst, falling back to copying and
+// removing the original when they are on different filesystems.
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if lerr, ok := err.(*os.LinkError); ok && lerr.Err == syscall.EXDEV {
+		if err := copyFile(src, dst); err != nil {
+			return err
+		}
+		return os.Remove(src)
+	}
+	return err
+}
+
+// pruneDir removes dir and its parents, for as long as they are empty.
+// It never goes above the current working directory.
+func pruneDir(dir string) {
+	cwd, _ := os.Getwd()
+	for {
+		abs, err := filepath.Abs(dir)
+		if err != nil || abs == cwd || os.Remove(abs) != nil {
+			return
+		}
+		rel, err := filepath.Rel(cwd, filepath.Dir(abs))
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return
+		}
+		dir = filepath.Dir(abs)
+	}
+}
+
+type RenameCommand struct {
+	ui        cli.Ui
+	wg        sync.WaitGroup
+	sanitizer *Sanitizer
+	root      string
+	where     *Query
+}
+
+func (cmd *RenameCommand) Process(op *renameOp, pattern *Template) {
+	defer cmd.wg.Done()
+
+	tags, err := readTags(op.src)
+	if err != nil {
+		op.err = err
+		return
+	}
+
+	name := pattern.Render(tags, cmd.sanitizer.Value)
+	if strings.TrimSpace(name) == "" {
+		op.err = fmt.Errorf("pattern renders an empty name")
+		return
+	}
+	name = cmd.sanitizer.Path(name, filepath.Ext(op.src))
+	root := cmd.root
+	if root == "" {
+		root = filepath.Dir(op.src)
+	}
+	op.dst = filepath.Join(root, name)
+
+	src, _ := filepath.Abs(op.src)
+	if dst, _ := filepath.Abs(op.dst); src == dst {
+		op.dst = op.src
+	}
+}
+
+// Check reports files which are not where the pattern puts them,
+// grouped by their current directory.
+func (cmd *RenameCommand) Check(ops []*renameOp) int {
+	dirs := []string{}
+	groups := map[string][]*renameOp{}
+	for _, op := range ops {
+		if op.err != nil || op.src == op.dst {
+			continue
+		}
+		dir := filepath.Dir(op.src)
+		if _, ok := groups[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		groups[dir] = append(groups[dir], op)
+	}
+
+	for _, dir := range dirs {
+		cmd.ui.Output(fmt.Sprintf("`%s`:", dir))
+		for _, op := range groups[dir] {
+			cmd.ui.Output(fmt.Sprintf("\t`%s` -> `%s`", filepath.Base(op.src), op.dst))
+		}
+	}
+
+	if len(dirs) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func (cmd *RenameCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("rename", flag.ContinueOnError)
+	flags.Usage = func() { cmd.ui.Output(cmd.Help()) }
+	yes := flags.Bool("Y", false, "")
+	table := flags.String("c", "portable", "")
+	var replace listFlag
+	flags.Var(&replace, "R", "")
+	maxLen := flags.Int("l", 255, "")
+	ascii := flags.Bool("a", false, "")
+	flags.StringVar(&cmd.root, "d", "", "")
+	r := &renamer{}
+	flags.BoolVar(&r.suffix, "s", false, "")
+	flags.BoolVar(&r.copy, "C", false, "")
+	flags.BoolVar(&r.companions, "m", false, "")
+	flags.BoolVar(&r.prune, "p", false, "")
+	check := flags.Bool("check", false, "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	args = flags.Args()
+	if len(args) < 2 {
+		cmd.ui.Output(cmd.Help())
+		return 1
+	}
+
+	var err error
+	cmd.sanitizer, err = NewSanitizer(*table, replace, *maxLen, *ascii)
+	if err != nil {
+		cmd.ui.Error(err.Error())
+		return 1
+	}
+
+	pattern, err := ParseTemplate(args[0])
+	if err != nil {
+		cmd.ui.Error(err.Error())
+		return 1
+	}
+
+	files := cmd.where.Filter(cmd.ui, args[1:])
+	ops := make([]*renameOp, len(files))
+	for i, file := range files {
+		ops[i] = &renameOp{src: filepath.Clean(file)}
+		cmd.wg.Add(1)
+		go cmd.Process(ops[i], pattern)
+	}
+	cmd.wg.Wait()
+
+	failed := false
+	for _, op := range ops {
+		if op.err != nil {
+			cmd.ui.Error(fmt.Sprintf("`%s`: %s", op.src, op.err))
+			failed = true
+		}
+	}
+	if *check {
+		if cmd.Check(ops) != 0 || failed {
+			return 1
+		}
+		return 0
+	}
+	if failed {
+		return 1
+	}
+
+	if r.companions {
+		ops = r.addCompanions(ops)
+	}
+	if clashes := r.plan(ops); len(clashes) > 0 {
+		for _, clash := range clashes {
+			cmd.ui.Error(clash)
+		}
+		cmd.ui.Error("Nothing was renamed, use -s to add suffixes to clashing names")
+		return 1
+	}
+
+	n := 0
+	for _, op := range ops {
+		if op.src != op.dst {
+			cmd.ui.Output(fmt.Sprintf("`%s` -> `%s`", op.src, op.dst))
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+
+	if !*yes {
+		verb := "Rename"
+		if r.copy {
+			verb = "Copy"
+		}
+		answer, err := cmd.ui.Ask(fmt.Sprintf("%s %d file(s)? [y/N]", verb, n))
+		if err != nil || strings.ToLower(strings.TrimSpace(answer)) != "y" {
+			return 1
+		}
+	}
+
+	if err := r.apply(ops); err != nil {
+		cmd.ui.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func (cmd *RenameCommand) Help() string {
+	return strings.TrimSpace(`
+usage: tu r [-Y] [-s] [-d ROOT] [-C] [-m] [-p] [--check]
+	[-c TABLE] [-R CHAR=STRING]... [-l MAX] [-a] PATTERN FILES...
+
+-Y Answer Yes to all questions.
+-s Append " (N)" suffix to clashing names instead of refusing to rename.
+-d ROOT Directory the PATTERN is relative to.
+	If not specified, uses each file's own directory.
+-C Copy files instead of moving them.
+-m Take companion files (covers, .cue, .log, .lrc) along.
+-p Remove source directories left empty.
+--check Do not touch anything, only report files whose path differs
+	from the PATTERN, grouped by directory.
+-c TABLE Illegal characters replacement table, 'portable' (default) or 'posix'.
+-R CHAR=STRING Replace CHAR with STRING in tag values, can be repeated.
+-l MAX Maximum length of a path component in bytes (defaults to 255,
+	at least 16).
+-a Transliterate tag values to ASCII.
+
+PATTERN is a string with placeholders in form of %{<name>}
+	or just %<name>, if <name> is one word.
+Placeholders can also contain:
+	fallbacks: %{albumartist|artist}, first non-empty tag is used
+	defaults: %{date:-Unknown}, used when the value is empty
+	zero-padding: %{tracknumber:02}
+	functions: %{artist:first:upper}, one of upper, lower, title, first
+		and replace(FROM,TO)
+	`)
+}
+
+func (cmd *RenameCommand) Synopsis() string {
+	return "Renames files by applying tags to a pattern"
+}
@@ -0,0 +1,216 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/assert"
+)
+
+func renameDir(t *testing.T, files ...string) string {
+	dir, err := ioutil.TempDir("", "tu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(file), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestPlanRenames_Batch(t *testing.T) {
+	dir := renameDir(t, "a.flac", "b.flac")
+	defer os.RemoveAll(dir)
+
+	ops := []*renameOp{
+		{src: filepath.Join(dir, "a.flac"), dst: filepath.Join(dir, "x.flac")},
+		{src: filepath.Join(dir, "b.flac"), dst: filepath.Join(dir, "X.flac")},
+	}
+	assert.Len(t, (&renamer{}).plan(ops), 1)
+
+	assert.Empty(t, (&renamer{suffix: true}).plan(ops))
+	assert.Equal(t, filepath.Join(dir, "x.flac"), ops[0].dst)
+	assert.Equal(t, filepath.Join(dir, "X (2).flac"), ops[1].dst)
+}
+
+func TestPlanRenames_Existing(t *testing.T) {
+	dir := renameDir(t, "a.flac", "B.flac")
+	defer os.RemoveAll(dir)
+
+	ops := []*renameOp{
+		{src: filepath.Join(dir, "a.flac"), dst: filepath.Join(dir, "b.flac")},
+	}
+	assert.Len(t, (&renamer{}).plan(ops), 1)
+
+	assert.Empty(t, (&renamer{suffix: true}).plan(ops))
+	assert.Equal(t, filepath.Join(dir, "b (2).flac"), ops[0].dst)
+}
+
+func TestApplyRenames_Cycle(t *testing.T) {
+	dir := renameDir(t, "a.flac", "b.flac")
+	defer os.RemoveAll(dir)
+
+	ops := []*renameOp{
+		{src: filepath.Join(dir, "a.flac"), dst: filepath.Join(dir, "b.flac")},
+		{src: filepath.Join(dir, "b.flac"), dst: filepath.Join(dir, "a.flac")},
+	}
+	assert.Empty(t, (&renamer{}).plan(ops))
+	assert.NoError(t, (&renamer{}).apply(ops))
+
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "a.flac"))
+	assert.Equal(t, "b.flac", string(data))
+	data, _ = ioutil.ReadFile(filepath.Join(dir, "b.flac"))
+	assert.Equal(t, "a.flac", string(data))
+}
+
+func TestApplyRenames_Rollback(t *testing.T) {
+	dir := renameDir(t, "a.flac", "b.flac", "c.flac", "file")
+	defer os.RemoveAll(dir)
+
+	ops := []*renameOp{
+		{src: filepath.Join(dir, "a.flac"), dst: filepath.Join(dir, "b.flac")},
+		{src: filepath.Join(dir, "b.flac"), dst: filepath.Join(dir, "file", "b.flac")},
+		{src: filepath.Join(dir, "c.flac"), dst: filepath.Join(dir, "d.flac")},
+	}
+	err := (&renamer{}).apply(ops)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), filepath.Join(dir, ".tu-1-b.flac"))
+	}
+
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "b.flac"))
+	assert.Equal(t, "a.flac", string(data))
+	data, _ = ioutil.ReadFile(filepath.Join(dir, "c.flac"))
+	assert.Equal(t, "c.flac", string(data))
+	matches, _ := filepath.Glob(filepath.Join(dir, ".tu-*"))
+	assert.Equal(t, []string{filepath.Join(dir, ".tu-1-b.flac")}, matches)
+}
+
+func TestRenamer_Tree(t *testing.T) {
+	dir := renameDir(t, "a.flac", "a.lrc", "b.flac", "cover.jpg", "notes.txt")
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	r := &renamer{companions: true}
+	ops := r.addCompanions([]*renameOp{
+		{src: filepath.Join(dir, "a.flac"), dst: filepath.Join(out, "X", "01 - A.flac")},
+		{src: filepath.Join(dir, "b.flac"), dst: filepath.Join(out, "X", "02 - B.flac")},
+	})
+	assert.Len(t, ops, 4)
+	assert.Empty(t, r.plan(ops))
+	assert.NoError(t, r.apply(ops))
+
+	for _, file := range []string{"01 - A.flac", "01 - A.lrc", "02 - B.flac", "cover.jpg"} {
+		_, err := os.Stat(filepath.Join(out, "X", file))
+		assert.NoError(t, err, file)
+	}
+	_, err := os.Stat(filepath.Join(dir, "notes.txt"))
+	assert.NoError(t, err)
+}
+
+func TestRenamer_SplitAlbum(t *testing.T) {
+	dir := renameDir(t, "a.flac", "b.flac", "cover.jpg")
+	defer os.RemoveAll(dir)
+
+	r := &renamer{companions: true}
+	ops := r.addCompanions([]*renameOp{
+		{src: filepath.Join(dir, "a.flac"), dst: filepath.Join(dir, "X", "a.flac")},
+		{src: filepath.Join(dir, "b.flac"), dst: filepath.Join(dir, "Y", "b.flac")},
+	})
+
+	assert.Len(t, ops, 2)
+}
+
+func TestRenamer_Copy(t *testing.T) {
+	dir := renameDir(t, "a.flac", "b.flac")
+	defer os.RemoveAll(dir)
+
+	r := &renamer{copy: true}
+	ops := []*renameOp{
+		{src: filepath.Join(dir, "a.flac"), dst: filepath.Join(dir, "b.flac")},
+	}
+	assert.Len(t, r.plan(ops), 1)
+
+	ops[0].dst = filepath.Join(dir, "sub", "c.flac")
+	assert.Empty(t, r.plan(ops))
+	assert.NoError(t, r.apply(ops))
+
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "sub", "c.flac"))
+	assert.Equal(t, "a.flac", string(data))
+	_, err := os.Stat(filepath.Join(dir, "a.flac"))
+	assert.NoError(t, err)
+}
+
+func TestRenamer_Prune(t *testing.T) {
+	dir := renameDir(t)
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "old", "album")
+	os.MkdirAll(src, 0755)
+	ioutil.WriteFile(filepath.Join(src, "a.flac"), nil, 0644)
+
+	cwd, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(cwd)
+
+	r := &renamer{prune: true}
+	ops := []*renameOp{
+		{src: filepath.Join("old", "album", "a.flac"), dst: filepath.Join("new", "a.flac")},
+	}
+	assert.NoError(t, r.apply(ops))
+
+	_, err := os.Stat(filepath.Join(dir, "old"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(dir)
+	assert.NoError(t, err)
+}
+
+func TestRenameCommand_Check(t *testing.T) {
+	ui := new(cli.MockUi)
+	cmd := &RenameCommand{ui: ui}
+
+	code := cmd.Check([]*renameOp{
+		{src: filepath.Join("A", "1.flac"), dst: filepath.Join("A", "01 - X.flac")},
+		{src: filepath.Join("B", "2.flac"), dst: filepath.Join("B", "2.flac")},
+		{src: filepath.Join("A", "3.flac"), dst: filepath.Join("C", "3.flac")},
+	})
+
+	assert.Equal(t, 1, code)
+	assert.Equal(t, fmt.Sprintf(
+		"`A`:\n\t`1.flac` -> `%s`\n\t`3.flac` -> `%s`\n",
+		filepath.Join("A", "01 - X.flac"), filepath.Join("C", "3.flac"),
+	), ui.OutputWriter.String())
+}
+
+func TestRenameCommand_CheckClean(t *testing.T) {
+	cmd := &RenameCommand{ui: new(cli.MockUi)}
+
+	code := cmd.Check([]*renameOp{{src: "1.flac", dst: "1.flac"}})
+
+	assert.Equal(t, 0, code)
+}
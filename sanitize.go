@@ -0,0 +1,192 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+type sanitizeTable struct {
+	chars    map[rune]string
+	trimDots bool
+}
+
+var sanitizeTables = map[string]sanitizeTable{
+	"posix": {chars: map[rune]string{
+		'/': "-",
+	}},
+	"portable": {chars: map[rune]string{
+		'/':  "-",
+		'\\': "-",
+		':':  " -",
+		'*':  "_",
+		'?':  "",
+		'"':  "'",
+		'<':  "(",
+		'>':  ")",
+		'|':  "-",
+	}, trimDots: true},
+}
+
+var transliterations = map[rune]string{
+	'ł': "l", 'Ł': "L", 'ß': "ss", 'æ': "ae", 'Æ': "AE", 'ø': "o", 'Ø': "O",
+	'œ': "oe", 'Œ': "OE", 'đ': "d", 'Đ': "D", 'ð': "d", 'Ð': "D", 'þ': "th",
+	'Þ': "Th", 'ı': "i", '‘': "'", '’': "'", '‚': "'", '“': "\"", '”': "\"",
+	'„': "\"", '–': "-", '—': "-", '…': "...", '×': "x",
+
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'А': "A", 'Б': "B", 'В': "V", 'Г': "G", 'Д': "D", 'Е': "E", 'Ё': "E",
+	'Ж': "Zh", 'З': "Z", 'И': "I", 'Й': "Y", 'К': "K", 'Л': "L", 'М': "M",
+	'Н': "N", 'О': "O", 'П': "P", 'Р': "R", 'С': "S", 'Т': "T", 'У': "U",
+	'Ф': "F", 'Х': "Kh", 'Ц': "Ts", 'Ч': "Ch", 'Ш': "Sh", 'Щ': "Shch",
+	'Ъ': "", 'Ы': "Y", 'Ь': "", 'Э': "E", 'Ю': "Yu", 'Я': "Ya",
+}
+
+// transliterate approximates text with ASCII characters.
+// Diacritics are stripped, anything else unknown becomes '_'.
+func transliterate(text string) string {
+	var out bytes.Buffer
+	for _, r := range norm.NFD.String(text) {
+		switch {
+		case r < utf8.RuneSelf:
+			out.WriteRune(r)
+		case unicode.Is(unicode.Mn, r):
+		default:
+			if repl, ok := transliterations[r]; ok {
+				out.WriteString(repl)
+			} else {
+				out.WriteRune('_')
+			}
+		}
+	}
+	return out.String()
+}
+
+// truncate shortens text to at most max bytes, preferably at a word boundary.
+func truncate(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	if max <= 0 {
+		return ""
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	if space := strings.LastIndex(text[:cut], " "); space > cut*2/3 {
+		cut = space
+	}
+	return strings.TrimRight(text[:cut], " ,-_.")
+}
+
+// minMaxLen is the lowest accepted path component length limit, leaving
+// room for a name besides the extension.
+const minMaxLen = 16
+
+// Sanitizer turns rendered tag values into safe path components.
+type Sanitizer struct {
+	table  sanitizeTable
+	maxLen int
+	ascii  bool
+}
+
+func NewSanitizer(table string, replace []string, maxLen int, ascii bool) (*Sanitizer, error) {
+	base, ok := sanitizeTables[table]
+	if !ok {
+		return nil, fmt.Errorf("unknown sanitization table `%s`", table)
+	}
+	if maxLen < minMaxLen {
+		return nil, fmt.Errorf("maximum length %d is too short, use at least %d", maxLen, minMaxLen)
+	}
+
+	s := &Sanitizer{
+		table:  sanitizeTable{chars: map[rune]string{}, trimDots: base.trimDots},
+		maxLen: maxLen,
+		ascii:  ascii,
+	}
+	for r, repl := range base.chars {
+		s.table.chars[r] = repl
+	}
+	for _, rule := range replace {
+		r, size := utf8.DecodeRuneInString(rule)
+		if size == 0 || !strings.HasPrefix(rule[size:], "=") {
+			return nil, fmt.Errorf("invalid replacement `%s`, expected CHAR=STRING", rule)
+		}
+		s.table.chars[r] = rule[size+1:]
+	}
+	return s, nil
+}
+
+// Value prepares a single tag value for use inside of a path component.
+func (s *Sanitizer) Value(value string) string {
+	value = norm.NFC.String(value)
+	if s.ascii {
+		value = transliterate(value)
+	}
+
+	var out bytes.Buffer
+	for _, r := range value {
+		if repl, ok := s.table.chars[r]; ok {
+			out.WriteString(repl)
+		} else if !unicode.IsControl(r) {
+			out.WriteRune(r)
+		}
+	}
+	return out.String()
+}
+
+// Path fixes up every component of a rendered name,
+// keeping ext attached to the last one.
+func (s *Sanitizer) Path(name, ext string) string {
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if s.table.trimDots {
+			part = strings.TrimRight(part, ". ")
+		}
+		if part == "" || part == "." || part == ".." {
+			part = "_"
+		}
+		if i < len(parts)-1 {
+			parts[i] = truncate(part, s.maxLen)
+		} else {
+			max := s.maxLen - len(ext)
+			if max < 1 {
+				max = 1
+			}
+			parts[i] = truncate(part, max) + ext
+		}
+	}
+	return filepath.FromSlash(strings.Join(parts, "/"))
+}
@@ -0,0 +1,86 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var SanitizerPathTests = []struct {
+	table    string
+	replace  []string
+	maxLen   int
+	ascii    bool
+	value    string
+	expected string
+}{
+	{"portable", nil, 255, false, "Who: What?", "Who - What.flac"},
+	{"portable", nil, 255, false, "AC/DC", "AC-DC.flac"},
+	{"portable", nil, 255, false, "And Then...", "And Then.flac"},
+	{"posix", nil, 255, false, "And Then...", "And Then....flac"},
+	{"posix", nil, 255, false, "Who: What?", "Who: What?.flac"},
+	{"portable", []string{":=_"}, 255, false, "Who: What?", "Who_ What.flac"},
+	{"portable", nil, 255, false, "Zöe", "Zöe.flac"},
+	{"portable", nil, 255, true, "Żółta Łódź", "Zolta Lodz.flac"},
+	{"portable", nil, 255, true, "Кино", "Kino.flac"},
+	{"portable", nil, 255, false, "..", "_.flac"},
+	{"portable", nil, 20, false, "a very long song title", "a very long.flac"},
+	{"portable", nil, 16, false, "żżżżżżżż", "żżżżż.flac"},
+}
+
+func TestSanitizer_Path(t *testing.T) {
+	for i, tt := range SanitizerPathTests {
+		s, err := NewSanitizer(tt.table, tt.replace, tt.maxLen, tt.ascii)
+		assert.NoError(t, err)
+
+		actual := s.Path(s.Value(tt.value), ".flac")
+
+		assert.Equal(t, tt.expected, actual, fmt.Sprintf("%d", i))
+	}
+}
+
+func TestSanitizer_PathComponents(t *testing.T) {
+	s, _ := NewSanitizer("portable", nil, 255, false)
+
+	actual := s.Path(strings.Join([]string{
+		s.Value("AC/DC"), s.Value("1980 - Back in Black."), s.Value("Hells Bells"),
+	}, "/"), ".flac")
+
+	assert.Equal(t, "AC-DC/1980 - Back in Black/Hells Bells.flac", actual)
+}
+
+func TestNewSanitizer_Errors(t *testing.T) {
+	_, err := NewSanitizer("dos", nil, 255, false)
+	assert.Error(t, err)
+
+	_, err = NewSanitizer("portable", []string{"::"}, 255, false)
+	assert.Error(t, err)
+
+	for _, maxLen := range []int{-1, 0, 5, 15} {
+		_, err = NewSanitizer("portable", nil, maxLen, false)
+		assert.Error(t, err, fmt.Sprintf("%d", maxLen))
+	}
+}
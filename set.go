@@ -0,0 +1,298 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mitchellh/cli"
+)
+
+type setMode int
+
+const (
+	setReplace setMode = iota
+	setAppend
+	setRemove
+	setIfEmpty
+)
+
+var setModes = map[string]setMode{
+	"-a":         setAppend,
+	"-d":         setRemove,
+	"--if-empty": setIfEmpty,
+}
+
+// setOp is a single TAG VALUE pair given to tu s.
+type setOp struct {
+	mode     setMode
+	key      string
+	value    string
+	template *Template
+	// sidecar is an extension of a file, next to each processed one,
+	// to read the value from.
+	sidecar string
+}
+
+// readValue reads a tag value from file, without the trailing newline.
+func readValue(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// renderSets fills in values of ops for file, either from templates using
+// tags, or from sidecar files. Ops which cannot be filled in are skipped.
+func renderSets(ops []*setOp, file string, tags Tags) ([]*setOp, []error) {
+	var rendered []*setOp
+	var errs []error
+	for _, op := range ops {
+		switch {
+		case op.template != nil:
+			value := op.template.Render(tags, nil)
+			op = &setOp{mode: op.mode, key: op.key, value: value}
+		case op.sidecar != "":
+			name := strings.TrimSuffix(file, filepath.Ext(file)) + op.sidecar
+			value, err := readValue(name)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("skipping %s: %s", op.key, err))
+				continue
+			}
+			op = &setOp{mode: op.mode, key: op.key, value: value}
+		}
+		rendered = append(rendered, op)
+	}
+	return rendered, errs
+}
+
+// applySets returns tagutil actions needed to apply ops to tags.
+// Only keys whose values actually change are written.
+func applySets(tags Tags, ops []*setOp) []string {
+	var keys []string
+	values := map[string][]string{}
+	replaced := map[string]bool{}
+
+	for _, op := range ops {
+		lkey := strings.ToLower(op.key)
+		if _, ok := values[lkey]; !ok {
+			keys = append(keys, op.key)
+			values[lkey] = tags.Values(op.key)
+		}
+		current := values[lkey]
+
+		switch op.mode {
+		case setReplace:
+			if !replaced[lkey] {
+				current = nil
+				replaced[lkey] = true
+			}
+			current = append(current, op.value)
+		case setAppend:
+			if !contains(current, op.value) {
+				current = append(current, op.value)
+			}
+		case setRemove:
+			var kept []string
+			for _, value := range current {
+				if value != op.value {
+					kept = append(kept, value)
+				}
+			}
+			current = kept
+		case setIfEmpty:
+			if len(tags.Values(op.key)) == 0 {
+				current = append(current, op.value)
+			}
+		}
+		values[lkey] = current
+	}
+
+	var actions []string
+	for _, key := range keys {
+		if value := values[strings.ToLower(key)]; !equalValues(tags.Values(key), value) {
+			actions = append(actions, tagActions(key, value)...)
+		}
+	}
+	return actions
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type SetCommand struct {
+	ui    cli.Ui
+	wg    sync.WaitGroup
+	ops   []*setOp
+	audio bool
+	where *Query
+}
+
+func (cmd *SetCommand) Process(file string) {
+	defer cmd.wg.Done()
+
+	tags, err := readTags(file)
+	if err != nil {
+		cmd.ui.Error(fmt.Sprintf("`%s`: %s", file, err))
+		return
+	}
+
+	props := append(append(Tags{}, tags...), fileProps(file)...)
+	if cmd.audio {
+		audio, err := readAudioProps(file)
+		if err != nil {
+			cmd.ui.Error(fmt.Sprintf("`%s`: %s", file, err))
+			return
+		}
+		props = append(props, audio...)
+	}
+
+	ops, errs := renderSets(cmd.ops, file, props)
+	for _, err := range errs {
+		cmd.ui.Error(fmt.Sprintf("`%s`: %s", file, err))
+	}
+
+	actions := applySets(tags, ops)
+	if len(actions) == 0 {
+		return
+	}
+	if err := writeTags(file, actions); err != nil {
+		cmd.ui.Error(fmt.Sprintf("`%s`: %s", file, err))
+	}
+}
+
+func (cmd *SetCommand) Run(args []string) int {
+	files := []string{}
+
+	op := &setOp{}
+	infiles := false
+	sidecar := false
+	for _, arg := range args {
+		if arg == "--" {
+			infiles = true
+			continue
+		}
+
+		if infiles {
+			files = append(files, arg)
+			continue
+		}
+
+		if op.key == "" {
+			if mode, ok := setModes[arg]; ok {
+				op.mode = mode
+			} else {
+				op.key = arg
+			}
+		} else if arg == "--from-sidecar" && !sidecar {
+			sidecar = true
+		} else {
+			op.value = arg
+			if sidecar {
+				op.sidecar = arg
+				if !strings.HasPrefix(arg, ".") {
+					op.sidecar = "." + arg
+				}
+				sidecar = false
+			} else if strings.HasPrefix(arg, "@@") {
+				op.value = arg[1:]
+			} else if strings.HasPrefix(arg, "@") {
+				value, err := readValue(arg[1:])
+				if err != nil {
+					cmd.ui.Error(err.Error())
+					return 1
+				}
+				op.value = value
+			} else if strings.Contains(arg, "%{") {
+				template, err := ParseTemplate(arg)
+				if err != nil {
+					cmd.ui.Error(err.Error())
+					return 1
+				}
+				for _, name := range template.Names() {
+					cmd.audio = cmd.audio || contains(audioProps, strings.ToLower(name))
+				}
+				op.template = template
+			}
+			cmd.ops = append(cmd.ops, op)
+			op = &setOp{}
+		}
+	}
+
+	if len(cmd.ops) == 0 || len(files) == 0 || op.key != "" || op.mode != setReplace || sidecar {
+		cmd.ui.Output(cmd.Help())
+		return 1
+	}
+
+	for _, file := range cmd.where.Filter(cmd.ui, files) {
+		cmd.wg.Add(1)
+		go cmd.Process(file)
+	}
+
+	cmd.wg.Wait()
+	return 0
+}
+
+func (cmd *SetCommand) Help() string {
+	return strings.TrimSpace(`
+usage: tu s <[-a | -d | --if-empty] TAG [VALUE | --from-sidecar EXT]>...
+	-- FILES...
+
+Repeating a TAG sets all of its values, in order.
+
+-a	Append VALUE to TAG, unless already there.
+-d	Remove VALUE from TAG, other values are kept.
+--if-empty	Set TAG to VALUE only if the file does not have it yet.
+
+VALUE can contain %{<name>} placeholders, filled in separately for every
+	file, with the same functions as in tu r. Besides tags, <name> can be
+	one of _path, _dirname, _filename, _ext (file name parts) or
+	_duration, _length, _bitrate, _samplerate, _channels, _codec
+	(audio properties, require ffprobe). In such a VALUE, a % which
+	does not start a placeholder is kept as is and %% stands for a %.
+
+VALUE starting with @ is read from the named file instead,
+	use @@ for a literal @ at the beginning.
+
+--from-sidecar EXT	Read the value from a file next to each processed
+	one, with the same name, but extension EXT, e.g. .lrc or .txt.
+	Files without one are skipped.
+	`)
+}
+
+func (cmd *SetCommand) Synopsis() string {
+	return "Sets tags to values in files"
+}
@@ -0,0 +1,107 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var setTestTags = Tags{
+	{"GENRE", "Jazz"}, {"GENRE", "Blues"}, {"ARTIST", "Miles Davis"},
+}
+
+var ApplySetsTests = []struct {
+	ops      []*setOp
+	expected []string
+}{
+	{[]*setOp{{mode: setReplace, key: "genre", value: "Rock"}}, []string{"set:genre=Rock"}},
+	{[]*setOp{
+		{mode: setReplace, key: "genre", value: "Rock"}, {mode: setReplace, key: "genre", value: "Pop"},
+	}, []string{"set:genre=Rock", "add:genre=Pop"}},
+	{[]*setOp{{mode: setReplace, key: "artist", value: "Miles Davis"}}, nil},
+	{[]*setOp{{mode: setAppend, key: "genre", value: "Fusion"}}, []string{
+		"set:genre=Jazz", "add:genre=Blues", "add:genre=Fusion",
+	}},
+	{[]*setOp{{mode: setAppend, key: "genre", value: "Jazz"}}, nil},
+	{[]*setOp{{mode: setAppend, key: "mood", value: "Calm"}}, []string{"set:mood=Calm"}},
+	{[]*setOp{{mode: setRemove, key: "genre", value: "Jazz"}}, []string{"set:genre=Blues"}},
+	{[]*setOp{{mode: setRemove, key: "genre", value: "Rock"}}, nil},
+	{[]*setOp{
+		{mode: setRemove, key: "genre", value: "Jazz"}, {mode: setRemove, key: "genre", value: "Blues"},
+	}, []string{"clear:genre"}},
+	{[]*setOp{{mode: setIfEmpty, key: "genre", value: "Rock"}}, nil},
+	{[]*setOp{{mode: setIfEmpty, key: "date", value: "1959"}}, []string{"set:date=1959"}},
+	{[]*setOp{
+		{mode: setRemove, key: "genre", value: "Blues"}, {mode: setAppend, key: "genre", value: "Modal"},
+	}, []string{"set:genre=Jazz", "add:genre=Modal"}},
+}
+
+func TestApplySets(t *testing.T) {
+	for i, tt := range ApplySetsTests {
+		actual := applySets(setTestTags, tt.ops)
+
+		assert.Equal(t, tt.expected, actual, fmt.Sprintf("%d", i))
+	}
+}
+
+func TestRenderSets(t *testing.T) {
+	template, err := ParseTemplate("ripped from %{_dirname}, by %{artist:upper}")
+	assert.NoError(t, err)
+	ops := []*setOp{
+		{mode: setReplace, key: "comment", template: template},
+		{mode: setAppend, key: "genre", value: "Jazz"},
+	}
+	tags := append(Tags{{"artist", "Miles Davis"}}, fileProps("/music/Kind of Blue/01.flac")...)
+
+	actual, errs := renderSets(ops, "01.flac", tags)
+
+	assert.Empty(t, errs)
+	assert.Equal(t, "ripped from Kind of Blue, by MILES DAVIS", actual[0].value)
+	assert.Nil(t, actual[0].template)
+	assert.Equal(t, ops[1], actual[1])
+}
+
+func TestRenderSets_Sidecar(t *testing.T) {
+	dir := renameDir(t, "01.flac", "02.flac")
+	defer os.RemoveAll(dir)
+	lyrics := "So what?\nSo what!"
+	if err := ioutil.WriteFile(filepath.Join(dir, "01.lrc"), []byte(lyrics+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ops := []*setOp{{mode: setReplace, key: "lyrics", sidecar: ".lrc"}}
+
+	actual, errs := renderSets(ops, filepath.Join(dir, "01.flac"), nil)
+
+	assert.Empty(t, errs)
+	assert.Equal(t, lyrics, actual[0].value)
+
+	actual, errs = renderSets(ops, filepath.Join(dir, "02.flac"), nil)
+
+	assert.Len(t, errs, 1)
+	assert.Empty(t, actual)
+}
@@ -0,0 +1,435 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mitchellh/cli"
+)
+
+// pathColumn holds file paths in a tag table.
+const pathColumn = "_path"
+
+var tableFormats = []string{"csv", "tsv", "json"}
+
+// tableFormat returns format given explicitly, or guessed from file extension.
+func tableFormat(format, file string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), ".")
+		if !contains(tableFormats, format) {
+			format = "csv"
+		}
+	}
+	if !contains(tableFormats, format) {
+		return "", fmt.Errorf("unknown table format `%s`", format)
+	}
+	return format, nil
+}
+
+// joinValues joins values into a single cell. Backslashes and sep
+// inside of values are escaped with a backslash, see splitValues.
+func joinValues(values []string, sep string) string {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		value = strings.Replace(value, "\\", "\\\\", -1)
+		if sep != "" {
+			value = strings.Replace(value, sep, "\\"+sep, -1)
+		}
+		escaped[i] = value
+	}
+	return strings.Join(escaped, sep)
+}
+
+// splitValues splits a cell written by joinValues back into values.
+func splitValues(cell, sep string) []string {
+	var values []string
+	var value bytes.Buffer
+	for i := 0; i < len(cell); i++ {
+		switch {
+		case cell[i] == '\\' && sep != "" && strings.HasPrefix(cell[i+1:], sep):
+			value.WriteString(sep)
+			i += len(sep)
+		case cell[i] == '\\' && i+1 < len(cell) && cell[i+1] == '\\':
+			value.WriteByte('\\')
+			i++
+		case sep != "" && strings.HasPrefix(cell[i:], sep):
+			values = append(values, value.String())
+			value.Reset()
+			i += len(sep) - 1
+		default:
+			value.WriteByte(cell[i])
+		}
+	}
+	return append(values, value.String())
+}
+
+type tableRow struct {
+	path string
+	// values are keyed with column names. A column missing here
+	// is left alone, while an empty one clears the tag.
+	values map[string][]string
+}
+
+// actions returns tagutil actions making tags equal to row.
+func (row *tableRow) actions(tags Tags, columns []string) []string {
+	var actions []string
+	for _, column := range columns {
+		values, ok := row.values[column]
+		current := tags.Values(column)
+		if len(values) == 0 && strings.Join(current, "") == "" {
+			// An empty cell stands for a tag with an empty value as well.
+			continue
+		}
+		if ok && !equalValues(current, values) {
+			actions = append(actions, tagActions(column, values)...)
+		}
+	}
+	return actions
+}
+
+// tagTable holds tags of many files, one row per file, one column per tag.
+type tagTable struct {
+	columns []string
+	rows    []*tableRow
+}
+
+func newTagTable(paths []string, tags []Tags) *tagTable {
+	t := &tagTable{}
+	for i, path := range paths {
+		row := &tableRow{path: path, values: map[string][]string{}}
+		for _, key := range tags[i].Keys() {
+			column := ""
+			for _, c := range t.columns {
+				if strings.EqualFold(c, key) {
+					column = c
+					break
+				}
+			}
+			if column == "" {
+				column = key
+				t.columns = append(t.columns, key)
+			}
+			row.values[column] = tags[i].Values(key)
+		}
+		t.rows = append(t.rows, row)
+	}
+	return t
+}
+
+func (t *tagTable) Write(w io.Writer, format, sep string) error {
+	if format == "json" {
+		out := make([]map[string]interface{}, len(t.rows))
+		for i, row := range t.rows {
+			out[i] = map[string]interface{}{pathColumn: row.path}
+			for column, values := range row.values {
+				out[i][column] = values
+			}
+		}
+		data, err := json.MarshalIndent(out, "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if format == "tsv" {
+		writer.Comma = '\t'
+	}
+	writer.Write(append([]string{pathColumn}, t.columns...))
+	for _, row := range t.rows {
+		record := []string{row.path}
+		for _, column := range t.columns {
+			record = append(record, joinValues(row.values[column], sep))
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func readTagTable(r io.Reader, format, sep string) (*tagTable, error) {
+	if format == "json" {
+		return readJSONTable(r)
+	}
+
+	reader := csv.NewReader(r)
+	if format == "tsv" {
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	}
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 || len(records[0]) == 0 || records[0][0] != pathColumn {
+		return nil, fmt.Errorf("first column has to be `%s`", pathColumn)
+	}
+
+	t := &tagTable{columns: records[0][1:]}
+	for i, record := range records[1:] {
+		if record[0] == "" {
+			return nil, fmt.Errorf("line %d: empty `%s`", i+2, pathColumn)
+		}
+		row := &tableRow{path: record[0], values: map[string][]string{}}
+		for j, column := range t.columns {
+			var values []string
+			if record[j+1] != "" {
+				values = splitValues(record[j+1], sep)
+			}
+			row.values[column] = values
+		}
+		t.rows = append(t.rows, row)
+	}
+	return t, nil
+}
+
+func readJSONTable(r io.Reader) (*tagTable, error) {
+	var in []map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return nil, err
+	}
+
+	t := &tagTable{}
+	for i, entry := range in {
+		row := &tableRow{values: map[string][]string{}}
+		for key, raw := range entry {
+			if key == pathColumn {
+				if err := json.Unmarshal(raw, &row.path); err != nil {
+					return nil, fmt.Errorf("row %d: %s", i+1, err)
+				}
+				continue
+			}
+			var values []string
+			if err := json.Unmarshal(raw, &values); err != nil {
+				var value string
+				if err := json.Unmarshal(raw, &value); err != nil {
+					return nil, fmt.Errorf("row %d: `%s` is neither a string nor a list of strings", i+1, key)
+				}
+				values = []string{value}
+			}
+			row.values[key] = values
+			if !contains(t.columns, key) {
+				t.columns = append(t.columns, key)
+			}
+		}
+		if row.path == "" {
+			return nil, fmt.Errorf("row %d: empty `%s`", i+1, pathColumn)
+		}
+		t.rows = append(t.rows, row)
+	}
+	sort.Strings(t.columns)
+	return t, nil
+}
+
+type ExportCommand struct {
+	ui    cli.Ui
+	where *Query
+}
+
+func (cmd *ExportCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.Usage = func() { cmd.ui.Output(cmd.Help()) }
+	formatFlag := flags.String("f", "", "")
+	output := flags.String("o", "", "")
+	sep := flags.String("s", "; ", "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	files := flags.Args()
+	if len(files) < 1 {
+		cmd.ui.Output(cmd.Help())
+		return 1
+	}
+	files = cmd.where.Filter(cmd.ui, files)
+
+	format, err := tableFormat(*formatFlag, *output)
+	if err != nil {
+		cmd.ui.Error(err.Error())
+		return 1
+	}
+
+	tags := make([]Tags, len(files))
+	errs := make([]error, len(files))
+	var wg sync.WaitGroup
+	for i, file := range files {
+		wg.Add(1)
+		go func(i int, file string) {
+			defer wg.Done()
+			tags[i], errs[i] = readTags(file)
+		}(i, file)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			cmd.ui.Error(fmt.Sprintf("`%s`: %s", files[i], err))
+			return 1
+		}
+	}
+
+	w := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			cmd.ui.Error(err.Error())
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := newTagTable(files, tags).Write(w, format, *sep); err != nil {
+		cmd.ui.Error(err.Error())
+		return 1
+	}
+	return 0
+}
+
+func (cmd *ExportCommand) Help() string {
+	return strings.TrimSpace(`
+usage: tu x [-f FORMAT] [-o FILE] [-s SEP] FILES...
+
+Exports tags of FILES into a single table, one row per file
+	and one column per tag. The first column, _path, holds file paths.
+
+-f FORMAT One of csv, tsv or json.
+	If not specified, guessed from FILE extension, defaults to csv.
+-o FILE Write to FILE instead of the standard output.
+-s SEP Separator joining multiple values of a tag in csv and tsv
+	(defaults to "; "). Lists are used in json. SEP and backslashes
+	inside of values are escaped with a backslash.
+	`)
+}
+
+func (cmd *ExportCommand) Synopsis() string {
+	return "Exports tags to a CSV, TSV or JSON table"
+}
+
+type ImportCommand struct {
+	ui    cli.Ui
+	wg    sync.WaitGroup
+	where *Query
+}
+
+func (cmd *ImportCommand) Process(row *tableRow, columns []string) {
+	defer cmd.wg.Done()
+
+	tags, err := readTags(row.path)
+	if err != nil {
+		cmd.ui.Error(fmt.Sprintf("`%s`: %s", row.path, err))
+		return
+	}
+
+	actions := row.actions(tags, columns)
+	if len(actions) == 0 {
+		return
+	}
+	cmd.ui.Output(fmt.Sprintf("Processing `%s`", row.path))
+	if err := writeTags(row.path, actions); err != nil {
+		cmd.ui.Error(fmt.Sprintf("`%s`: %s", row.path, err))
+	}
+}
+
+func (cmd *ImportCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.Usage = func() { cmd.ui.Output(cmd.Help()) }
+	formatFlag := flags.String("f", "", "")
+	sep := flags.String("s", "; ", "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	args = flags.Args()
+	if len(args) != 1 {
+		cmd.ui.Output(cmd.Help())
+		return 1
+	}
+
+	format, err := tableFormat(*formatFlag, args[0])
+	if err != nil {
+		cmd.ui.Error(err.Error())
+		return 1
+	}
+
+	in := io.Reader(os.Stdin)
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			cmd.ui.Error(err.Error())
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+	table, err := readTagTable(in, format, *sep)
+	if err != nil {
+		cmd.ui.Error(fmt.Sprintf("`%s`: %s", args[0], err))
+		return 1
+	}
+
+	paths := make([]string, len(table.rows))
+	for i, row := range table.rows {
+		paths[i] = row.path
+	}
+	matched := cmd.where.Filter(cmd.ui, paths)
+
+	for _, row := range table.rows {
+		if !contains(matched, row.path) {
+			continue
+		}
+		cmd.wg.Add(1)
+		go cmd.Process(row, table.columns)
+	}
+
+	cmd.wg.Wait()
+	return 0
+}
+
+func (cmd *ImportCommand) Help() string {
+	return strings.TrimSpace(`
+usage: tu i [-f FORMAT] [-s SEP] TABLE
+
+Applies tags from TABLE (as written by tu x, - for the standard input)
+	back to files, writing only the changed tags. An empty cell
+	removes the tag, tags without a column are left alone.
+
+-f FORMAT One of csv, tsv or json.
+	If not specified, guessed from TABLE extension, defaults to csv.
+-s SEP Separator splitting multiple values of a tag in csv and tsv
+	(defaults to "; "). Use \SEP for SEP and \\ for a backslash
+	inside of a value.
+	`)
+}
+
+func (cmd *ImportCommand) Synopsis() string {
+	return "Imports tags from a CSV, TSV or JSON table"
+}
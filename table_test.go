@@ -0,0 +1,168 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testTable() *tagTable {
+	return newTagTable([]string{"01.flac", "02.flac"}, []Tags{
+		{{"TITLE", "So What"}, {"GENRE", "Jazz"}, {"GENRE", "Modal"}},
+		{{"title", "Freddie, \"Freeloader\""}, {"DATE", "1959"}},
+	})
+}
+
+func TestNewTagTable(t *testing.T) {
+	table := testTable()
+
+	assert.Equal(t, []string{"TITLE", "GENRE", "DATE"}, table.columns)
+	assert.Equal(t, []string{"Freddie, \"Freeloader\""}, table.rows[1].values["TITLE"])
+	assert.Nil(t, table.rows[1].values["GENRE"])
+}
+
+func TestTagTable_WriteCSV(t *testing.T) {
+	var out bytes.Buffer
+
+	err := testTable().Write(&out, "csv", "; ")
+
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"_path,TITLE,GENRE,DATE",
+		"01.flac,So What,Jazz; Modal,",
+		"02.flac,\"Freddie, \"\"Freeloader\"\"\",,1959",
+		"",
+	}, "\n"), out.String())
+}
+
+func TestTagTable_RoundTrip(t *testing.T) {
+	for _, format := range tableFormats {
+		var out bytes.Buffer
+		expected := testTable()
+		assert.NoError(t, expected.Write(&out, format, "; "))
+
+		actual, err := readTagTable(&out, format, "; ")
+
+		assert.NoError(t, err, format)
+		assert.Len(t, actual.rows, 2, format)
+		for i, row := range actual.rows {
+			assert.Equal(t, expected.rows[i].path, row.path, format)
+			for _, column := range expected.columns {
+				assert.Equal(t, expected.rows[i].values[column], row.values[column], format)
+			}
+		}
+	}
+}
+
+func TestReadTagTable_Errors(t *testing.T) {
+	_, err := readTagTable(strings.NewReader("TITLE,_path\nx,01.flac\n"), "csv", "; ")
+	assert.Error(t, err)
+
+	_, err = readTagTable(strings.NewReader("_path,TITLE\n,x\n"), "csv", "; ")
+	assert.Error(t, err)
+
+	_, err = readTagTable(strings.NewReader(`[{"_path": "01.flac", "TITLE": 3}]`), "json", "; ")
+	assert.Error(t, err)
+}
+
+var TableRowActionsTests = []struct {
+	values   map[string][]string
+	expected []string
+}{
+	{map[string][]string{"TITLE": {"So What"}, "GENRE": {"Jazz", "Modal"}}, nil},
+	{map[string][]string{"TITLE": {"So What?"}}, []string{"set:TITLE=So What?"}},
+	{map[string][]string{"GENRE": {"Jazz"}}, []string{"set:GENRE=Jazz"}},
+	{map[string][]string{"GENRE": nil, "DATE": {"1959"}}, []string{
+		"clear:GENRE", "set:DATE=1959",
+	}},
+}
+
+func TestTableRow_Actions(t *testing.T) {
+	tags := Tags{{"TITLE", "So What"}, {"GENRE", "Jazz"}, {"GENRE", "Modal"}}
+	columns := []string{"TITLE", "GENRE", "DATE"}
+
+	for i, tt := range TableRowActionsTests {
+		row := &tableRow{path: "01.flac", values: tt.values}
+
+		actual := row.actions(tags, columns)
+
+		assert.Equal(t, tt.expected, actual, fmt.Sprintf("%d", i))
+	}
+}
+
+func TestTableFormat(t *testing.T) {
+	format, _ := tableFormat("", "tags.TSV")
+	assert.Equal(t, "tsv", format)
+
+	format, _ = tableFormat("", "")
+	assert.Equal(t, "csv", format)
+
+	format, _ = tableFormat("json", "tags.csv")
+	assert.Equal(t, "json", format)
+
+	_, err := tableFormat("xls", "")
+	assert.Error(t, err)
+}
+
+var JoinValuesTests = []struct {
+	values   []string
+	expected string
+}{
+	{[]string{"Jazz", "Modal"}, "Jazz; Modal"},
+	{[]string{"Recorded live; remastered"}, `Recorded live\; remastered`},
+	{[]string{`C:\dir`, "x"}, `C:\\dir; x`},
+	{[]string{`a\`, "b"}, `a\\; b`},
+}
+
+func TestJoinValues(t *testing.T) {
+	for i, tt := range JoinValuesTests {
+		actual := joinValues(tt.values, "; ")
+
+		assert.Equal(t, tt.expected, actual, fmt.Sprintf("%d", i))
+		assert.Equal(t, tt.values, splitValues(actual, "; "), fmt.Sprintf("%d", i))
+	}
+}
+
+func TestTagTable_RoundTripNoActions(t *testing.T) {
+	tags := []Tags{
+		{{"COMMENT", "Recorded live; remastered"}, {"GENRE", "Jazz"}, {"GENRE", "Modal"}},
+		{{"COMMENT", `C:\rips\; x`}, {"TITLE", ""}},
+	}
+	paths := []string{"01.flac", "02.flac"}
+	for _, format := range tableFormats {
+		var out bytes.Buffer
+		table := newTagTable(paths, tags)
+		assert.NoError(t, table.Write(&out, format, "; "))
+
+		actual, err := readTagTable(&out, format, "; ")
+
+		assert.NoError(t, err, format)
+		for i, row := range actual.rows {
+			assert.Empty(t, row.actions(tags[i], actual.columns), format)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Tag is a single tag entry. A key may appear multiple times
+// in a file, giving the tag multiple values.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// Tags are all the tag entries of a file, in order.
+type Tags []Tag
+
+// Get returns the first value of key, compared case-insensitively.
+func (tags Tags) Get(key string) string {
+	for _, tag := range tags {
+		if strings.EqualFold(tag.Key, key) {
+			return tag.Value
+		}
+	}
+	return ""
+}
+
+// Values returns all values of key, in order, compared case-insensitively.
+func (tags Tags) Values(key string) []string {
+	var values []string
+	for _, tag := range tags {
+		if strings.EqualFold(tag.Key, key) {
+			values = append(values, tag.Value)
+		}
+	}
+	return values
+}
+
+// Keys returns distinct keys, in order of their first appearance.
+func (tags Tags) Keys() []string {
+	var keys []string
+	for _, tag := range tags {
+		seen := false
+		for _, key := range keys {
+			if strings.EqualFold(key, tag.Key) {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			keys = append(keys, tag.Key)
+		}
+	}
+	return keys
+}
+
+// tagAliases map Vorbis comment names onto names used
+// by other formats, keyed with lower case file extension.
+var tagAliases = map[string]map[string]string{
+	".mp3": {"tracknumber": "track", "date": "year"},
+}
+
+// canonicalKey returns Vorbis comment name of key used in file.
+func canonicalKey(key, file string) string {
+	for name, alias := range tagAliases[strings.ToLower(filepath.Ext(file))] {
+		if strings.EqualFold(key, alias) {
+			return name
+		}
+	}
+	return key
+}
+
+// formatKey returns name of a key (given in any format) used in file.
+func formatKey(key, from, to string) string {
+	key = canonicalKey(key, from)
+	if alias, ok := tagAliases[strings.ToLower(filepath.Ext(to))][strings.ToLower(key)]; ok {
+		return alias
+	}
+	return key
+}
+
+// tagActions returns tagutil actions replacing all values of key with values.
+func tagActions(key string, values []string) []string {
+	if len(values) == 0 {
+		return []string{fmt.Sprintf("clear:%s", key)}
+	}
+	actions := []string{fmt.Sprintf("set:%s=%s", key, values[0])}
+	for _, value := range values[1:] {
+		actions = append(actions, fmt.Sprintf("add:%s=%s", key, value))
+	}
+	return actions
+}
+
+func readTags(file string) (Tags, error) {
+	tagutil := exec.Command("tagutil", "-F", "json", file)
+	out, err := tagutil.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []map[string]string
+	if err := json.Unmarshal(out, &entries); err != nil {
+		return nil, err
+	}
+	var tags Tags
+	for _, entry := range entries {
+		keys := make([]string, 0, len(entry))
+		for k := range entry {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			tags = append(tags, Tag{k, entry[k]})
+		}
+	}
+	return tags, nil
+}
+
+// writeTags applies tagutil actions to file.
+func writeTags(file string, actions []string) error {
+	tagutil := exec.Command("tagutil", append(actions, file)...)
+	if out, err := tagutil.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s", msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// nonTextTags hold identifiers, numbers, dates and the like, rather than
+// human-readable text. Names are lower case, trailing `*` matches any suffix.
+var nonTextTags = []string{
+	"musicbrainz_*", "acoustid_*", "replaygain_*", "itun*",
+	"isrc", "barcode", "upc", "asin", "catalognumber",
+	"date", "year", "originaldate", "originalyear", "releasedate",
+	"tracknumber", "tracktotal", "totaltracks",
+	"discnumber", "disctotal", "totaldiscs",
+	"bpm", "initialkey", "length", "language", "script", "compilation",
+	"releasecountry", "releasestatus", "releasetype",
+	"url", "www*", "website", "encoder", "encodedby", "encoding",
+}
+
+var (
+	rUUID    = regexp.MustCompile(`^(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	rURL     = regexp.MustCompile(`^(?i)[a-z][a-z0-9+.-]*://`)
+	rNumeric = regexp.MustCompile(`^[\d\s.,:/+-]*$`)
+	rISRC    = regexp.MustCompile(`^[A-Z]{2}-?[A-Z0-9]{3}-?\d{2}-?\d{5}$`)
+)
+
+// isTextTag reports whether tag is a human-readable field,
+// judging by its name first and by its value second.
+func isTextTag(key, value string) bool {
+	key = strings.ToLower(key)
+	for _, name := range nonTextTags {
+		if strings.HasSuffix(name, "*") {
+			if strings.HasPrefix(key, name[:len(name)-1]) {
+				return false
+			}
+		} else if key == name {
+			return false
+		}
+	}
+
+	value = strings.TrimSpace(value)
+	switch {
+	case rUUID.MatchString(value), rURL.MatchString(value):
+		return false
+	case rNumeric.MatchString(value), rISRC.MatchString(value):
+		return false
+	}
+	return true
+}
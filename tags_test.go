@@ -0,0 +1,106 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var IsTextTagTests = []struct {
+	key      string
+	value    string
+	expected bool
+}{
+	{"TITLE", "the lord of the rings", true},
+	{"ARTIST", "U2", true},
+	{"ALBUM", "1989", false},
+	{"MUSICBRAINZ_TRACKID", "whatever", false},
+	{"musicbrainz_albumid", "whatever", false},
+	{"REPLAYGAIN_TRACK_GAIN", "-7.50 dB", false},
+	{"ISRC", "USRC17607839", false},
+	{"COMMENT", "USRC17607839", false},
+	{"COMMENT", "https://example.com/album", false},
+	{"COMMENT", "a4fe8e6c-0f34-4e1e-8d1b-8a8e3d8d1f5b", false},
+	{"DATE", "2011", false},
+	{"DATE", "2011-05-01", false},
+	{"TRACKNUMBER", "3/12", false},
+	{"WWWARTIST", "example.com", false},
+	{"GENRE", "", false},
+	{"GENRE", "rock", true},
+}
+
+func TestIsTextTag(t *testing.T) {
+	for i, tt := range IsTextTagTests {
+		actual := isTextTag(tt.key, tt.value)
+
+		assert.Equal(t, tt.expected, actual, fmt.Sprintf("%d", i))
+	}
+}
+
+var testTags = Tags{
+	{"ARTIST", "Kaczmarski"}, {"title", "Lot Ikara"},
+	{"artist", "Gintrowski"}, {"ARTIST", "Łapiński"},
+}
+
+func TestTags_Get(t *testing.T) {
+	assert.Equal(t, "Kaczmarski", testTags.Get("artist"))
+	assert.Equal(t, "Lot Ikara", testTags.Get("TITLE"))
+	assert.Equal(t, "", testTags.Get("album"))
+}
+
+func TestTags_Values(t *testing.T) {
+	expected := []string{"Kaczmarski", "Gintrowski", "Łapiński"}
+
+	assert.Equal(t, expected, testTags.Values("Artist"))
+	assert.Nil(t, testTags.Values("album"))
+}
+
+func TestTags_Keys(t *testing.T) {
+	assert.Equal(t, []string{"ARTIST", "title"}, testTags.Keys())
+}
+
+var TagActionsTests = []struct {
+	values   []string
+	expected []string
+}{
+	{nil, []string{"clear:artist"}},
+	{[]string{"A"}, []string{"set:artist=A"}},
+	{[]string{"A", "B", "C"}, []string{"set:artist=A", "add:artist=B", "add:artist=C"}},
+}
+
+func TestTagActions(t *testing.T) {
+	for i, tt := range TagActionsTests {
+		actual := tagActions("artist", tt.values)
+
+		assert.Equal(t, tt.expected, actual, fmt.Sprintf("%d", i))
+	}
+}
+
+func TestFormatKey(t *testing.T) {
+	assert.Equal(t, "track", formatKey("TRACKNUMBER", "a.flac", "b.mp3"))
+	assert.Equal(t, "tracknumber", formatKey("track", "a.mp3", "b.flac"))
+	assert.Equal(t, "year", formatKey("year", "a.mp3", "b.MP3"))
+	assert.Equal(t, "ARTIST", formatKey("ARTIST", "a.flac", "b.mp3"))
+}
@@ -0,0 +1,176 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/KenjiTakahashi/tu/titlecase"
+)
+
+type tagFilter func(value string) string
+
+var (
+	rPadding = regexp.MustCompile(`^\d+$`)
+	rReplace = regexp.MustCompile(`^replace\(([^,]*),([^)]*)\)$`)
+)
+
+var tagFilters = map[string]tagFilter{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"title": func(value string) string {
+		return titlecase.Convert(value, nil, nil)
+	},
+	"first": func(value string) string {
+		r, size := utf8.DecodeRuneInString(strings.TrimSpace(value))
+		if size == 0 {
+			return ""
+		}
+		return string(r)
+	},
+}
+
+func padFilter(width int) tagFilter {
+	return func(value string) string {
+		value = strings.TrimSpace(strings.SplitN(value, "/", 2)[0])
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return value
+		}
+		return fmt.Sprintf("%0*d", width, n)
+	}
+}
+
+func defaultFilter(def string) tagFilter {
+	return func(value string) string {
+		if value == "" {
+			return def
+		}
+		return value
+	}
+}
+
+// tagExpr is a compiled placeholder, i.e. everything between `%{` and `}`.
+//
+// It consists of a `|` separated list of tag names, first non-empty
+// of which is used, followed by any number of `:` prefixed filters.
+type tagExpr struct {
+	names   []string
+	filters []tagFilter
+}
+
+func parseTagExpr(in string) (*tagExpr, error) {
+	parts := strings.Split(in, ":")
+	expr := &tagExpr{names: strings.Split(parts[0], "|")}
+
+	for i, part := range parts[1:] {
+		switch {
+		case strings.HasPrefix(part, "-"):
+			def := strings.Join(parts[i+1:], ":")[1:]
+			expr.filters = append(expr.filters, defaultFilter(def))
+			return expr, nil
+		case rPadding.MatchString(part):
+			width, _ := strconv.Atoi(part)
+			expr.filters = append(expr.filters, padFilter(width))
+		case rReplace.MatchString(part):
+			m := rReplace.FindStringSubmatch(part)
+			from, to := m[1], m[2]
+			expr.filters = append(expr.filters, func(value string) string {
+				return strings.Replace(value, from, to, -1)
+			})
+		default:
+			filter, ok := tagFilters[part]
+			if !ok {
+				return nil, fmt.Errorf("unknown function `%s` in `%%{%s}`", part, in)
+			}
+			expr.filters = append(expr.filters, filter)
+		}
+	}
+	return expr, nil
+}
+
+func (expr *tagExpr) Eval(tags Tags) string {
+	var value string
+	for _, name := range expr.names {
+		if value = tags.Get(name); value != "" {
+			break
+		}
+	}
+	for _, filter := range expr.filters {
+		value = filter(value)
+	}
+	return value
+}
+
+// Template is a pattern which can be filled in with tag values.
+type Template struct {
+	pieces []*PatternPiece
+	exprs  []*tagExpr
+}
+
+func ParseTemplate(in string) (*Template, error) {
+	t := &Template{pieces: parsePattern(in)}
+	t.exprs = make([]*tagExpr, len(t.pieces))
+	for i, piece := range t.pieces {
+		if piece.Name == "" {
+			continue
+		}
+		expr, err := parseTagExpr(piece.Name)
+		if err != nil {
+			return nil, err
+		}
+		t.exprs[i] = expr
+	}
+	return t, nil
+}
+
+// Names returns all tag names used in the template.
+func (t *Template) Names() []string {
+	var names []string
+	for _, expr := range t.exprs {
+		if expr != nil {
+			names = append(names, expr.names...)
+		}
+	}
+	return names
+}
+
+// Render fills in the template, passing every value through clean first.
+func (t *Template) Render(tags Tags, clean func(string) string) string {
+	var out bytes.Buffer
+	for i, piece := range t.pieces {
+		if t.exprs[i] != nil {
+			value := t.exprs[i].Eval(tags)
+			if clean != nil {
+				value = clean(value)
+			}
+			out.WriteString(value)
+		}
+		out.WriteString(piece.Sep)
+	}
+	return out.String()
+}
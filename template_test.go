@@ -0,0 +1,95 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var TemplateRenderTests = []struct {
+	pattern  string
+	tags     Tags
+	expected string
+}{
+	{"%tracknumber - %title", Tags{
+		{"tracknumber", "01"}, {"title", "Lot Ikara"},
+	}, "01 - Lot Ikara"},
+	{"%{TITLE}", Tags{
+		{"title", "Lot Ikara"},
+	}, "Lot Ikara"},
+	{"%{albumartist|artist}", Tags{
+		{"artist", "Jacek Kaczmarski"},
+	}, "Jacek Kaczmarski"},
+	{"%{albumartist|artist}", Tags{
+		{"artist", "Jacek Kaczmarski"}, {"albumartist", "Various"},
+	}, "Various"},
+	{"%{date:-Unknown}", Tags{}, "Unknown"},
+	{"%{date:-Unknown: Year}", Tags{}, "Unknown: Year"},
+	{"%{date:-Unknown}", Tags{{"date", "2002"}}, "2002"},
+	{"%{tracknumber:02}", Tags{{"tracknumber", "3"}}, "03"},
+	{"%{tracknumber:03}", Tags{{"tracknumber", "3/12"}}, "003"},
+	{"%{tracknumber:02}", Tags{{"tracknumber", "A1"}}, "A1"},
+	{"%{artist:first:upper}/%artist", Tags{
+		{"artist", "łzy"},
+	}, "Ł/łzy"},
+	{"%{artist:lower}", Tags{{"artist", "ABBA"}}, "abba"},
+	{"%{title:title}", Tags{{"title", "lot ikara"}}, "Lot Ikara"},
+	{"%{title:replace( ,_)}", Tags{{"title", "Lot Ikara"}}, "Lot_Ikara"},
+	{"%{albumartist|artist:upper:-NONE}", Tags{}, "NONE"},
+	{"50% of %{artist}", Tags{{"artist", "ABBA"}}, "50% of ABBA"},
+	{"%{tracknumber}%% done", Tags{{"tracknumber", "30"}}, "30% done"},
+}
+
+func TestTemplate_Render(t *testing.T) {
+	for i, tt := range TemplateRenderTests {
+		template, err := ParseTemplate(tt.pattern)
+		assert.NoError(t, err)
+
+		actual := template.Render(tt.tags, nil)
+
+		assert.Equal(t, tt.expected, actual, fmt.Sprintf("%d", i))
+	}
+}
+
+func TestTemplate_RenderClean(t *testing.T) {
+	template, _ := ParseTemplate("%artist - %title")
+	s, _ := NewSanitizer("portable", nil, 255, false)
+
+	actual := template.Render(Tags{{"title", "AC/DC"}}, s.Value)
+
+	assert.Equal(t, " - AC-DC", actual)
+}
+
+func TestParseTemplate_Errors(t *testing.T) {
+	_, err := ParseTemplate("%{artist:bogus}")
+
+	assert.Error(t, err)
+}
+
+func TestTemplate_Names(t *testing.T) {
+	template, _ := ParseTemplate("%{albumartist|artist:upper} - %title (%{_length})")
+
+	assert.Equal(t, []string{"albumartist", "artist", "title", "_length"}, template.Names())
+}
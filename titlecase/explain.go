@@ -0,0 +1,118 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package titlecase
+
+import (
+	"fmt"
+)
+
+// Rule identifies what decided about the case of a word.
+type Rule int
+
+const (
+	// RuleCapitalized is the default, first letter is capitalized.
+	RuleCapitalized Rule = iota
+	// RuleSmallWord keeps a small word lowercase.
+	RuleSmallWord
+	// RuleSmallFirst capitalizes a small word starting a line.
+	RuleSmallFirst
+	// RuleSmallLast capitalizes a small word ending a line.
+	RuleSmallLast
+	// RuleSubPhrase capitalizes a small word starting a sub-phrase,
+	// e.g. after a colon.
+	RuleSubPhrase
+	// RuleInlinePeriod keeps words like "example.com" intact.
+	RuleInlinePeriod
+	// RuleMixedCase keeps words like "iTunes" intact.
+	RuleMixedCase
+	// RuleInitials keeps initials like "D.C." intact in all-caps lines.
+	RuleInitials
+	// RuleApostrophe capitalizes both parts of words like "o'reilly".
+	RuleApostrophe
+	// RuleElision handles elided prefixes like French "l'".
+	RuleElision
+	// RuleKeepCapitalized keeps already capitalized words, e.g. German nouns.
+	RuleKeepCapitalized
+	// RuleSentence lowercases words after the first one in sentence case.
+	RuleSentence
+	// RuleParticle capitalizes a small word being a phrasal verb particle.
+	RuleParticle
+	// RuleException writes a word exactly as given in exceptions.
+	RuleException
+	// RulePreHook means the pre hook returned a final word.
+	RulePreHook
+	// RulePostHook means the post hook changed the word.
+	RulePostHook
+	// RuleMode means the whole text was changed to lower or upper case.
+	RuleMode
+	// RuleConservative means mixed case text was left intact.
+	RuleConservative
+	// RuleVersion writes a version keyword, like "Remix", in its canonical form.
+	RuleVersion
+	// RuleAcronym writes a known acronym in upper case.
+	RuleAcronym
+	// RuleRomanNumeral writes a Roman numeral in upper case.
+	RuleRomanNumeral
+)
+
+var ruleNames = map[Rule]string{
+	RuleCapitalized:     "capitalized",
+	RuleSmallWord:       "small word",
+	RuleSmallFirst:      "small word first",
+	RuleSmallLast:       "small word last",
+	RuleSubPhrase:       "sub-phrase",
+	RuleInlinePeriod:    "inline period",
+	RuleMixedCase:       "mixed case",
+	RuleInitials:        "all-caps initials",
+	RuleApostrophe:      "apostrophe",
+	RuleElision:         "elision",
+	RuleKeepCapitalized: "already capitalized",
+	RuleSentence:        "sentence",
+	RuleParticle:        "phrasal verb particle",
+	RuleException:       "exception",
+	RulePreHook:         "pre hook",
+	RulePostHook:        "post hook",
+	RuleMode:            "mode",
+	RuleConservative:    "conservative",
+	RuleVersion:         "version keyword",
+	RuleAcronym:         "acronym",
+	RuleRomanNumeral:    "roman numeral",
+}
+
+func (r Rule) String() string {
+	if name, ok := ruleNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("Rule(%d)", int(r))
+}
+
+// Decision describes how a single word was converted.
+type Decision struct {
+	Line   int
+	Input  string
+	Output string
+	Rule   Rule
+}
+
+func (d Decision) String() string {
+	return fmt.Sprintf("%q -> %q (%s)", d.Input, d.Output, d.Rule)
+}
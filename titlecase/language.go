@@ -0,0 +1,90 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package titlecase
+
+import (
+	"strings"
+)
+
+// Language describes capitalization rules specific to a language.
+type Language struct {
+	// SmallWords are kept lowercase, unless first or last in a (sub)phrase.
+	// They are matched literally, ignoring case; a word given with a trailing
+	// period, like "vs.", matches with or without it.
+	SmallWords []string
+	// FirstOnly capitalizes only the first word of a line, or of
+	// a phrase after a dash or an opening bracket.
+	FirstOnly bool
+	// KeepCapitalized leaves words which are already capitalized intact,
+	// e.g. names or German nouns.
+	KeepCapitalized bool
+	// Elisions are prefixes (with the apostrophe stripped) which are
+	// treated as small words glued to the following word, e.g. French l'.
+	Elisions []string
+}
+
+// Languages contains rules for known languages, keyed by ISO 639-1 codes.
+var Languages = map[string]*Language{
+	"en": {
+		SmallWords: []string{
+			"a", "an", "and", "as", "at", "but", "by", "en", "for", "if", "in",
+			"of", "on", "or", "the", "to", "v.", "via", "vs.",
+		},
+	},
+	"pl": {
+		FirstOnly:       true,
+		KeepCapitalized: true,
+	},
+	"de": {
+		FirstOnly:       true,
+		KeepCapitalized: true,
+	},
+	"fr": {
+		SmallWords: []string{
+			"à", "au", "aux", "avec", "chez", "dans", "de", "des", "du", "en",
+			"et", "la", "le", "les", "mais", "ni", "ou", "par", "pour", "sans",
+			"sous", "sur", "un", "une",
+		},
+		Elisions: []string{"c", "d", "j", "l", "m", "n", "qu", "s", "t"},
+	},
+}
+
+var languageAliases = map[string]string{
+	"eng": "en", "english": "en",
+	"pol": "pl", "polish": "pl", "polski": "pl",
+	"deu": "de", "ger": "de", "german": "de", "deutsch": "de",
+	"fra": "fr", "fre": "fr", "french": "fr", "français": "fr",
+}
+
+// LookupLanguage finds rules for a language given by its ISO 639-1
+// or 639-2 code, English name or locale (e.g. pl_PL).
+func LookupLanguage(name string) (*Language, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if i := strings.IndexAny(name, "_-"); i != -1 {
+		name = name[:i]
+	}
+	if alias, ok := languageAliases[name]; ok {
+		name = alias
+	}
+	lang, ok := Languages[name]
+	return lang, ok
+}
@@ -0,0 +1,86 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package titlecase
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mode selects the kind of case transformation a Converter does.
+type Mode int
+
+const (
+	// TitleMode applies title case rules, this is the default.
+	TitleMode Mode = iota
+	// SentenceMode capitalizes only the first word of every line.
+	SentenceMode
+	// StartMode capitalizes every word, small words included.
+	StartMode
+	// LowerMode changes everything to lower case.
+	LowerMode
+	// UpperMode changes everything to upper case.
+	UpperMode
+)
+
+// Modes maps Mode names to values.
+var Modes = map[string]Mode{
+	"title":    TitleMode,
+	"sentence": SentenceMode,
+	"start":    StartMode,
+	"lower":    LowerMode,
+	"upper":    UpperMode,
+}
+
+// ParseMode returns Mode with the given name.
+func ParseMode(name string) (Mode, error) {
+	mode, ok := Modes[strings.ToLower(name)]
+	if !ok {
+		return TitleMode, fmt.Errorf("unknown mode `%s`", name)
+	}
+	return mode, nil
+}
+
+// ParseModeOptions returns Options selecting the mode with the given
+// name. Besides Modes, it accepts "conservative", which is TitleMode
+// applied only to text that is not in mixed case already.
+func ParseModeOptions(name string) (Options, error) {
+	if strings.ToLower(name) == "conservative" {
+		return Options{Mode: TitleMode, Conservative: true}, nil
+	}
+	mode, err := ParseMode(name)
+	return Options{Mode: mode}, err
+}
+
+func (m Mode) String() string {
+	for name, mode := range Modes {
+		if mode == m {
+			return name
+		}
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// mixedCase reports whether text contains both lower and upper case letters.
+func mixedCase(text string) bool {
+	return strings.ToLower(text) != text && strings.ToUpper(text) != text
+}
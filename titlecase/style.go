@@ -0,0 +1,154 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package titlecase
+
+import (
+	"strings"
+)
+
+// Hyphenation selects how parts of hyphenated compounds are capitalized.
+type Hyphenation int
+
+const (
+	// HyphenAll capitalizes every part, e.g. Up-To-Date.
+	HyphenAll Hyphenation = iota
+	// HyphenSmall capitalizes every part but small words, e.g. Up-to-Date.
+	HyphenSmall
+	// HyphenFirst capitalizes only the first part, e.g. Up-to-date.
+	HyphenFirst
+)
+
+// Style is a title case style guide.
+type Style struct {
+	// SmallWords are kept lowercase, unless first or last in a (sub)phrase.
+	// They are matched literally, ignoring case; a word given with a trailing
+	// period, like "vs.", matches with or without it.
+	SmallWords []string
+	// MinLength, if positive, makes small words of that many letters
+	// or more capitalized anyway.
+	MinLength int
+	// Hyphenated selects how hyphenated compounds are treated.
+	Hyphenated Hyphenation
+	// Particles following any of PhrasalVerbs are capitalized,
+	// even if they are small words, e.g. Give Up.
+	PhrasalVerbs []string
+	Particles    []string
+}
+
+var (
+	articles     = []string{"a", "an", "the"}
+	conjunctions = []string{"and", "but", "for", "nor", "or", "so", "yet"}
+	prepositions = []string{
+		"about", "above", "across", "after", "against", "along", "among",
+		"around", "as", "at", "before", "behind", "below", "beneath",
+		"beside", "between", "beyond", "by", "despite", "down", "during",
+		"except", "for", "from", "in", "inside", "into", "like", "near",
+		"of", "off", "on", "onto", "out", "outside", "over", "past", "per",
+		"since", "through", "throughout", "till", "to", "toward", "towards",
+		"under", "underneath", "until", "up", "upon", "v.", "via",
+		"vs.", "with", "within", "without",
+	}
+
+	phrasalVerbs = []string{
+		"back", "blow", "break", "bring", "burn", "call", "calm", "carry",
+		"check", "cheer", "come", "cut", "dream", "drop", "fall", "freak",
+		"get", "give", "go", "hang", "hold", "jump", "keep", "knock", "lay",
+		"let", "lie", "lift", "light", "lock", "look", "make", "move",
+		"pass", "pay", "pick", "play", "pull", "push", "put", "rock", "roll",
+		"run", "set", "shake", "shout", "show", "shut", "sit", "slow",
+		"speak", "spin", "stand", "stay", "switch", "take", "throw", "turn",
+		"wake", "walk", "work",
+		"blew", "broke", "brought", "burnt", "came", "fell", "gave", "gone",
+		"got", "held", "kept", "laid", "lit", "made", "ran", "sat", "shook",
+		"spoke", "spun", "stood", "threw", "took", "went", "woke",
+	}
+	objects = []string{
+		"her", "him", "it", "me", "that", "them", "this", "us", "you",
+	}
+	particles = []string{
+		"along", "around", "away", "back", "by", "down", "in", "off", "on",
+		"out", "over", "through", "up",
+	}
+)
+
+func concat(lists ...[]string) []string {
+	var out []string
+	for _, list := range lists {
+		out = append(out, list...)
+	}
+	return out
+}
+
+// NYT is the NY Times Manual of Style.
+var NYT = &Style{
+	SmallWords: Languages["en"].SmallWords,
+}
+
+// Styles contains known style guides, keyed by their lowercase names.
+var Styles = map[string]*Style{
+	"nyt": NYT,
+	// Chicago lowercases prepositions regardless of their length.
+	"chicago": {
+		SmallWords: concat(articles, conjunctions[:5], prepositions),
+		Hyphenated: HyphenSmall,
+	},
+	// AP and APA lowercase only words of three letters or less.
+	"ap": {
+		SmallWords: concat(articles, conjunctions, prepositions),
+		MinLength:  4,
+	},
+	"apa": {
+		SmallWords: concat(articles, conjunctions, prepositions),
+		MinLength:  4,
+	},
+	// Music capitalizes verb particles, e.g. Give It Up, Turn Off the Lights.
+	"music": {
+		SmallWords:   concat(articles, conjunctions[:5], prepositions),
+		MinLength:    4,
+		PhrasalVerbs: phrasalVerbs,
+		Particles:    particles,
+	},
+}
+
+// LookupStyle finds a style guide by its name.
+func LookupStyle(name string) (*Style, bool) {
+	style, ok := Styles[strings.ToLower(strings.TrimSpace(name))]
+	return style, ok
+}
+
+// verbForms returns verb together with its regular inflections,
+// so that "give" matches "gives", "giving" and "givin'" as well
+// (with apostrophes trimmed).
+func verbForms(verb string) []string {
+	stem := strings.TrimSuffix(verb, "e")
+	forms := []string{
+		verb, verb + "s", verb + "es", verb + "ed", verb + "d",
+		stem + "ing", stem + "in",
+	}
+	if n := len(verb); n >= 3 && strings.IndexByte("aeiou", verb[n-2]) != -1 &&
+		strings.IndexByte("aeiouwy", verb[n-1]) == -1 &&
+		strings.IndexByte("aeiou", verb[n-3]) == -1 {
+		double := verb + verb[n-1:]
+		forms = append(forms, double+"ed", double+"ing", double+"in")
+	}
+	return forms
+}
@@ -27,30 +27,38 @@
 // Additional hooks can be supplied to modify the standard behaviour,
 // see Convert documentation for further details.
 //
+// Rules for languages other than English are available as well,
+// see ConvertLanguage and Languages.
+//
+// For full control over the rules, build a Converter from Options.
+//
 // Original Perl version by: John Gruber http://daringfireball.net/ 10 May 2008
 // Python version by Stuart Colville http://muffinresearch.co.uk
 package titlecase
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 var (
-	rSmall = `a|an|and|as|at|but|by|en|for|if|in|of|on|or|the|to|v\.?|via|vs\.?`
-	rPunct = "!\"#$%&'‘()*+,\\-./:;?@[\\]_`{|}~"
+	punctChars = "!\"#$%&'‘’“”„«»()*+,-./:;?@[\\]_`{|}~"
+	rPunct     = "!\"#$%&'‘’“”„«»()*+,\\-./:;?@[\\]_`{|}~"
 
-	rSmallWords   = regexp.MustCompile(fmt.Sprintf(`(?i)^(%s)$`, rSmall))
-	rInlinePeriod = regexp.MustCompile(`(?i)[a-z][.][a-z]`)
-	rUCElsewhere  = regexp.MustCompile(fmt.Sprintf(`[%s]*?[a-zA-Z]+[A-Z]+?`, rPunct))
+	rInlinePeriod = regexp.MustCompile(`\p{L}[.]\p{L}`)
+	rUCElsewhere  = regexp.MustCompile(fmt.Sprintf(`[%s]*?\p{L}+\p{Lu}+?`, rPunct))
 	rCapFirst     = regexp.MustCompile(fmt.Sprintf(`^[%s]*?([\p{L}])`, rPunct))
-	rSmallFirst   = regexp.MustCompile(fmt.Sprintf(`(?i)^([%s]*)(%s)\b`, rPunct, rSmall))
-	rSmallLast    = regexp.MustCompile(fmt.Sprintf(`(?i)\b(%s)[%s]?$`, rSmall, rPunct))
-	rSubPhrase    = regexp.MustCompile(fmt.Sprintf(`([:.;?!][ ])(%s)`, rSmall))
-	rAposSecond   = regexp.MustCompile(`(?i)^[dol]{1}['‘]{1}[a-z]+$`)
-	rAllCaps      = regexp.MustCompile(fmt.Sprintf(`^[A-Z\s\d%s]+$`, rPunct))
-	rUCInitials   = regexp.MustCompile(`^(?:[A-Z]{1}\.{1}|[A-Z]{1}\.{1}[A-Z]{1})+$`)
+	rAposSecond   = regexp.MustCompile(`(?i)^([dol])(['‘’])(\p{L}+(?:['‘’]s)?)$`)
+	rAllCaps      = regexp.MustCompile(`^[^\p{Ll}]*\p{Lu}[^\p{Ll}]*$`)
+	rUCInitials   = regexp.MustCompile(`^(?:\p{Lu}\.|\p{Lu}\.\p{Lu})+$`)
+	rCapitalized  = regexp.MustCompile(fmt.Sprintf(`^[%s]*?\p{Lu}`, rPunct))
+	rRoman        = regexp.MustCompile(`(?i)^M{0,4}(?:CM|CD|D?C{0,3})(?:XC|XL|L?X{0,3})(?:IX|IV|V?I{0,3})$`)
+	rRomanSmall   = regexp.MustCompile(`(?i)^X{0,3}(?:IX|IV|V?I{0,3})$`)
 
 	rLines = regexp.MustCompile(`[\r\n]+`)
 	rWords = regexp.MustCompile(`[\t ]`)
@@ -62,6 +70,147 @@ type PreHook func(word string, allCaps bool) (string, bool)
 // PostHook defines Convert post hook function signature
 type PostHook func(word string, allCaps bool) string
 
+// Options configure a Converter.
+type Options struct {
+	// Language defaults to English.
+	Language *Language
+	// Style overrides small words of the Language.
+	Style *Style
+	// SmallWords, if not nil, overrides small words of both
+	// the Language and the Style.
+	SmallWords []string
+	// Exceptions are words and phrases which are always written exactly
+	// as given, no matter the letter case on input. They take precedence
+	// over all the other rules, hooks included. See also LoadExceptions.
+	Exceptions []string
+	// PreHook and PostHook are described in Convert documentation.
+	PreHook  PreHook
+	PostHook PostHook
+	// VersionKeywords are normalized to the given casing when found
+	// in a bracketed part or a part following a dash, e.g. (Live)
+	// or - Radio Edit. If nil, VersionKeywords variable is used.
+	VersionKeywords []string
+	// Acronyms are always written in upper case (or as given),
+	// also in all-caps text. If nil, Acronyms variable is used.
+	Acronyms []string
+	// Mode defaults to TitleMode.
+	Mode Mode
+	// Conservative leaves text which is already in mixed case intact,
+	// only entirely lower or upper case text is converted.
+	Conservative bool
+}
+
+// Converter applies title case rules configured by Options.
+//
+// All rules are compiled once, on creation, so a Converter
+// is cheap to reuse and safe for concurrent use.
+type Converter struct {
+	lang         *Language
+	mode         Mode
+	conservative bool
+	firstOnly    bool
+	minLength    int
+	hyphenated   Hyphenation
+	verbs        map[string]bool
+	particles    map[string]bool
+	preHook      PreHook
+	postHook     PostHook
+	exceptions   map[string]string
+	maxPhrase    int
+
+	versions   map[string]string
+	acronyms   map[string]string
+	smallWords *regexp.Regexp
+	elision    *regexp.Regexp
+}
+
+// NewConverter creates a Converter configured by opts.
+func NewConverter(opts Options) *Converter {
+	c := &Converter{
+		lang:         opts.Language,
+		mode:         opts.Mode,
+		conservative: opts.Conservative,
+		preHook:      opts.PreHook,
+		postHook:     opts.PostHook,
+		exceptions:   map[string]string{},
+	}
+	if c.lang == nil {
+		c.lang = Languages["en"]
+	}
+	c.firstOnly = c.mode == SentenceMode || (c.mode == TitleMode && c.lang.FirstOnly)
+
+	smallWords := c.lang.SmallWords
+	if opts.Style != nil {
+		smallWords = opts.Style.SmallWords
+		c.minLength = opts.Style.MinLength
+		c.hyphenated = opts.Style.Hyphenated
+		c.verbs = map[string]bool{}
+		for _, verb := range opts.Style.PhrasalVerbs {
+			for _, form := range verbForms(verb) {
+				c.verbs[form] = true
+			}
+		}
+		c.particles = map[string]bool{}
+		for _, particle := range opts.Style.Particles {
+			c.particles[particle] = true
+		}
+	}
+	if opts.SmallWords != nil {
+		smallWords = opts.SmallWords
+	}
+	if len(smallWords) > 0 && c.mode == TitleMode && !c.firstOnly {
+		c.smallWords = smallWordsPattern(smallWords)
+	}
+	if len(c.lang.Elisions) > 0 {
+		c.elision = regexp.MustCompile(fmt.Sprintf(
+			`(?i)^([%s]*)(%s)(['’])(.+)$`, rPunct, strings.Join(quoteWords(c.lang.Elisions), "|"),
+		))
+	}
+
+	versions := opts.VersionKeywords
+	if versions == nil {
+		versions = VersionKeywords
+	}
+	c.versions = map[string]string{}
+	for _, keyword := range versions {
+		c.versions[strings.ToLower(keyword)] = keyword
+	}
+
+	acronyms := opts.Acronyms
+	if acronyms == nil {
+		acronyms = Acronyms
+	}
+	c.acronyms = map[string]string{}
+	for _, acronym := range acronyms {
+		c.acronyms[strings.ToLower(acronym)] = acronym
+	}
+
+	for _, phrase := range opts.Exceptions {
+		words := rWords.Split(strings.TrimSpace(phrase), -1)
+		c.exceptions[strings.ToLower(strings.Join(words, " "))] = strings.Join(words, " ")
+		if len(words) > c.maxPhrase {
+			c.maxPhrase = len(words)
+		}
+	}
+
+	return c
+}
+
+// VersionKeywords are default Options.VersionKeywords.
+var VersionKeywords = []string{
+	"Acoustic", "Bonus", "Demo", "Edit", "Extended", "feat.", "ft.",
+	"Instrumental", "Live", "Mix", "Mono", "Remaster", "Remastered",
+	"Remix", "Stereo", "Version",
+}
+
+// Acronyms are default Options.Acronyms.
+var Acronyms = []string{
+	"BBC", "CBC", "CD", "DJ", "DVD", "EDM", "EP", "FM", "LP", "MTV",
+	"NASA", "NME", "NPR", "NYC", "R&B", "TV", "UFO", "UK", "USA", "USSR",
+}
+
+var defaultConverter = NewConverter(Options{})
+
 // Convert changes input string to conform to the NY Times Manual of Style.
 //
 // If preHook and/or postHook arguments are not nil, they will be run,
@@ -71,63 +220,381 @@ type PostHook func(word string, allCaps bool) string
 // Additionally, preHook returns true if the returned string is final,
 // or false if it should still be run through standard transformations.
 func Convert(text string, preHook PreHook, postHook PostHook) string {
+	c := *defaultConverter
+	c.preHook = preHook
+	c.postHook = postHook
+	return c.Convert(text)
+}
+
+// ConvertLanguage works like Convert, but uses capitalization rules
+// of the specified language instead of the English ones.
+//
+// It compiles the rules on every call, so for repeated use
+// prefer creating a Converter.
+func ConvertLanguage(text string, lang *Language, preHook PreHook, postHook PostHook) string {
+	return NewConverter(Options{
+		Language: lang,
+		PreHook:  preHook,
+		PostHook: postHook,
+	}).Convert(text)
+}
+
+// titleFirst title cases the first letter found in text.
+func titleFirst(text string) string {
+	for i, r := range text {
+		if unicode.IsLetter(r) {
+			return text[:i] + string(unicode.ToTitle(r)) + text[i+utf8.RuneLen(r):]
+		}
+	}
+	return text
+}
+
+// lower works like strings.ToLower, but also takes care
+// of the Greek final sigma.
+func lower(text string) string {
+	text = strings.ToLower(text)
+	if !strings.ContainsRune(text, 'σ') {
+		return text
+	}
+	runes := []rune(text)
+	for i, r := range runes {
+		if r != 'σ' || i == 0 || !unicode.IsLetter(runes[i-1]) {
+			continue
+		}
+		if i == len(runes)-1 || !unicode.IsLetter(runes[i+1]) {
+			runes[i] = 'ς'
+		}
+	}
+	return string(runes)
+}
+
+func (c *Converter) capitalize(word string) string {
+	sep := "-"
+	if strings.Contains(word, "/") && !strings.Contains(word, "//") {
+		sep = "/"
+	}
+	sepSplit := strings.Split(word, sep)
+	capFirst := make([]string, len(sepSplit))
+	for k, ss := range sepSplit {
+		switch {
+		case k > 0 && sep == "-" && c.hyphenated == HyphenFirst:
+			capFirst[k] = ss
+		case k > 0 && sep == "-" && c.hyphenated == HyphenSmall && c.isSmall(ss):
+			capFirst[k] = lower(ss)
+		default:
+			capFirst[k] = rCapFirst.ReplaceAllStringFunc(ss, titleFirst)
+		}
+	}
+	return strings.Join(capFirst, sep)
+}
+
+// smallWordsPattern returns a regexp matching any of words literally,
+// with the trailing period of a word like "vs." being optional.
+func smallWordsPattern(words []string) *regexp.Regexp {
+	alts := make([]string, len(words))
+	for i, word := range words {
+		if strings.HasSuffix(word, ".") {
+			alts[i] = regexp.QuoteMeta(strings.TrimSuffix(word, ".")) + `\.?`
+		} else {
+			alts[i] = regexp.QuoteMeta(word)
+		}
+	}
+	return regexp.MustCompile(fmt.Sprintf(`(?i)^(%s)$`, strings.Join(alts, "|")))
+}
+
+func quoteWords(words []string) []string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = regexp.QuoteMeta(word)
+	}
+	return quoted
+}
+
+// isSmall reports whether word should stay lowercase.
+func (c *Converter) isSmall(word string) bool {
+	if c.smallWords == nil || !c.smallWords.MatchString(word) {
+		return false
+	}
+	return c.minLength <= 0 || utf8.RuneCountInString(word) < c.minLength
+}
+
+// version returns canonical casing of word if it is a version keyword.
+func (c *Converter) version(word string) (string, bool) {
+	lead, core, trail := splitPunct(word)
+	if keyword, ok := c.versions[lower(core)]; ok {
+		return lead + keyword + trail, true
+	}
+	if strings.HasPrefix(trail, ".") {
+		if keyword, ok := c.versions[lower(core)+"."]; ok {
+			return lead + keyword + trail[1:], true
+		}
+	}
+	return "", false
+}
+
+// isParticle reports whether the j-th word is a particle of a phrasal verb,
+// e.g. "up" in "give up" or "give it up".
+func (c *Converter) isParticle(words []string, j int) bool {
+	if !c.particles[lower(strings.Trim(words[j], punctChars))] {
+		return false
+	}
+	for k := j - 1; k >= 0 && k >= j-2; k-- {
+		prev := lower(strings.Trim(words[k], punctChars))
+		if c.verbs[prev] {
+			return true
+		}
+		if !contains(objects, prev) {
+			return false
+		}
+	}
+	return false
+}
+
+// numeralMarkers are words that introduce a number, e.g. "Vol. II".
+var numeralMarkers = []string{
+	"act", "book", "chapter", "episode", "no", "nr", "number", "op",
+	"opus", "part", "pt", "vol", "volume",
+}
+
+// isNumeral reports whether the j-th word is a Roman numeral. Since many
+// numerals are words too ("xi", "vi", "mix"), only a word after a marker
+// such as "no." or "vol." is taken as any numeral; a word ending a phrase
+// after a capitalized name ("Richard III") is taken as one up to XXXIX.
+func isNumeral(words []string, tokens []*token, j int) bool {
+	core := strings.Trim(words[j], punctChars)
+	if core == "" || j == 0 || !rRoman.MatchString(core) {
+		return false
+	}
+	prev := strings.Trim(words[j-1], punctChars)
+	if contains(numeralMarkers, lower(prev)) {
+		return true
+	}
+	return len(core) > 1 && rRomanSmall.MatchString(core) &&
+		rCapitalized.MatchString(prev) &&
+		(tokens[j].last || tokens[j].trail != "")
+}
+
+func contains(list []string, word string) bool {
+	for _, w := range list {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+// exception returns replacement for the longest exception
+// found at the beginning of words, or nil if there is none.
+func (c *Converter) exception(words []string) []string {
+	n := c.maxPhrase
+	if len(words) < n {
+		n = len(words)
+	}
+	for ; n > 0; n-- {
+		if exact, ok := lookup(c.exceptions, strings.Join(words[:n], " ")); ok {
+			return strings.Split(exact, " ")
+		}
+	}
+	return nil
+}
+
+// lookup finds phrase in dict (keyed with lower case), ignoring surrounding
+// punctuation and a possessive suffix, which are kept in the result.
+func lookup(dict map[string]string, phrase string) (string, bool) {
+	if exact, ok := dict[strings.ToLower(phrase)]; ok {
+		return exact, true
+	}
+	trimmed := strings.Trim(phrase, punctChars)
+	for _, suffix := range []string{"", "'s", "’s"} {
+		core := trimmed
+		if suffix != "" {
+			if !strings.HasSuffix(strings.ToLower(core), suffix) {
+				continue
+			}
+			core = core[:len(core)-len(suffix)]
+		}
+		if core == "" {
+			continue
+		}
+		if exact, ok := dict[strings.ToLower(core)]; ok {
+			return strings.Replace(phrase, core, exact, 1), true
+		}
+	}
+	return "", false
+}
+
+// LoadExceptions reads exceptions from r, one word or phrase per line.
+// Empty lines and lines starting with '#' are skipped.
+func LoadExceptions(r io.Reader) ([]string, error) {
+	var exceptions []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		exceptions = append(exceptions, line)
+	}
+	return exceptions, scanner.Err()
+}
+
+// Convert changes input string according to the Converter's rules.
+func (c *Converter) Convert(text string) string {
+	output, _ := c.convert(text)
+	return output
+}
+
+// Explain converts text like Convert does, but instead of the result
+// it returns a trace of the decisions made about every word.
+func (c *Converter) Explain(text string) []Decision {
+	_, decisions := c.convert(text)
+	return decisions
+}
+
+func wholeText(text string, rule Rule, convert func(string) string) (string, []Decision) {
+	var decisions []Decision
+	for i, line := range rLines.Split(text, -1) {
+		for _, word := range rWords.Split(line, -1) {
+			decisions = append(decisions, Decision{
+				Line: i, Input: word, Output: convert(word), Rule: rule,
+			})
+		}
+	}
+	return convert(text), decisions
+}
+
+func (c *Converter) convert(text string) (string, []Decision) {
+	if c.conservative && mixedCase(text) {
+		return wholeText(text, RuleConservative, func(s string) string { return s })
+	}
+	switch c.mode {
+	case LowerMode:
+		return wholeText(text, RuleMode, lower)
+	case UpperMode:
+		return wholeText(text, RuleMode, strings.ToUpper)
+	}
+
+	lang := c.lang
 	input := rLines.Split(text, -1)
 	output := make([]string, len(input))
+	var decisions []Decision
 
 	for i, line := range input {
 		allCaps := rAllCaps.MatchString(line)
 		words := rWords.Split(line, -1)
+		tokens := tokenize(line)
 		tcLine := make([]string, len(words))
+		rules := make([]Rule, len(words))
 
-		for j, word := range words {
-			if preHook != nil {
+		for j := 0; j < len(words); j++ {
+			if exact := c.exception(words[j:]); exact != nil {
+				for k, word := range exact {
+					tcLine[j+k] = word
+					rules[j+k] = RuleException
+				}
+				j += len(exact) - 1
+				continue
+			}
+
+			word := words[j]
+			if c.preHook != nil {
 				stop := false
-				word, stop = preHook(word, allCaps)
+				word, stop = c.preHook(word, allCaps)
 				if stop {
 					tcLine[j] = word
+					rules[j] = RulePreHook
 					continue
 				}
 			}
 
+			_, core, _ := splitPunct(word)
+			if acronym, ok := lookup(c.acronyms, word); ok {
+				tcLine[j], rules[j] = acronym, RuleAcronym
+				continue
+			}
+			if isNumeral(words, tokens, j) {
+				tcLine[j], rules[j] = strings.ToUpper(word), RuleRomanNumeral
+				continue
+			}
+
 			if allCaps {
 				if rUCInitials.MatchString(word) {
 					tcLine[j] = word
+					rules[j] = RuleInitials
 					continue
 				}
-				word = strings.ToLower(word)
+				word = lower(word)
 			}
 
-			if rAposSecond.MatchString(word) {
-				tcLine[j] = rAposSecond.ReplaceAllStringFunc(word, strings.Title)
-			} else if rInlinePeriod.MatchString(word) || rUCElsewhere.MatchString(word) {
-				tcLine[j] = word
-			} else if rSmallWords.MatchString(word) {
-				tcLine[j] = strings.ToLower(word)
-			} else {
-				sep := "-"
-				if strings.Contains(word, "/") && !strings.Contains(word, "//") {
-					sep = "/"
+			tok := tokens[j]
+			_, core, _ = splitPunct(word)
+			if tok.suffix {
+				if keyword, ok := c.version(word); ok {
+					tcLine[j], rules[j] = keyword, RuleVersion
+					continue
+				}
+			}
+
+			if c.elision != nil && c.elision.MatchString(word) {
+				m := c.elision.FindStringSubmatch(word)
+				prefix := lower(m[2])
+				if tok.first {
+					prefix = titleFirst(prefix)
+				}
+				rest := m[4]
+				_, restCore, _ := splitPunct(rest)
+				if !c.firstOnly && (tok.last || !c.isSmall(restCore)) {
+					rest = c.capitalize(rest)
 				}
-				sepSplit := strings.Split(word, sep)
-				capFirst := make([]string, len(sepSplit))
-				for k, ss := range sepSplit {
-					capFirst[k] = rCapFirst.ReplaceAllStringFunc(ss, strings.ToTitle)
+				tcLine[j], rules[j] = m[1]+prefix+m[3]+rest, RuleElision
+			} else if lang.KeepCapitalized && rCapitalized.MatchString(word) {
+				tcLine[j], rules[j] = word, RuleKeepCapitalized
+			} else if !c.firstOnly && rAposSecond.MatchString(word) {
+				m := rAposSecond.FindStringSubmatch(word)
+				tcLine[j] = titleFirst(m[1]) + m[2] + titleFirst(m[3])
+				rules[j] = RuleApostrophe
+			} else if rInlinePeriod.MatchString(word) {
+				tcLine[j], rules[j] = word, RuleInlinePeriod
+			} else if rUCElsewhere.MatchString(word) {
+				tcLine[j], rules[j] = word, RuleMixedCase
+			} else if c.firstOnly {
+				if tok.first {
+					tcLine[j], rules[j] = c.capitalize(word), RuleCapitalized
+				} else {
+					tcLine[j], rules[j] = lower(word), RuleSentence
 				}
-				tcLine[j] = strings.Join(capFirst, sep)
+			} else if c.isSmall(core) {
+				switch {
+				case c.isParticle(words, j):
+					tcLine[j], rules[j] = c.capitalize(word), RuleParticle
+				case tok.subPhrase:
+					tcLine[j], rules[j] = c.capitalize(word), RuleSubPhrase
+				case tok.first:
+					tcLine[j], rules[j] = c.capitalize(word), RuleSmallFirst
+				case tok.last:
+					tcLine[j], rules[j] = c.capitalize(word), RuleSmallLast
+				default:
+					tcLine[j], rules[j] = lower(word), RuleSmallWord
+				}
+			} else {
+				tcLine[j], rules[j] = c.capitalize(word), RuleCapitalized
 			}
 
-			if postHook != nil {
-				tcLine[j] = postHook(tcLine[j], allCaps)
+			if c.postHook != nil {
+				hooked := c.postHook(tcLine[j], allCaps)
+				if hooked != tcLine[j] {
+					tcLine[j], rules[j] = hooked, RulePostHook
+				}
 			}
 		}
 
-		result := strings.Join(tcLine, " ")
-		result = rSmallFirst.ReplaceAllStringFunc(result, strings.Title)
-		result = rSmallLast.ReplaceAllStringFunc(result, strings.Title)
-		result = rSubPhrase.ReplaceAllStringFunc(result, strings.Title)
-
-		output[i] = result
+		for j, word := range words {
+			decisions = append(decisions, Decision{
+				Line: i, Input: word, Output: tcLine[j], Rule: rules[j],
+			})
+		}
+		output[i] = strings.Join(tcLine, " ")
 	}
 
-	return strings.Join(output, "\n")
+	return strings.Join(output, "\n"), decisions
 }
@@ -24,6 +24,7 @@ package titlecase
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -145,3 +146,349 @@ func TestConvert_PostHook(t *testing.T) {
 
 	assert.Equal(t, "mock", actual)
 }
+
+var ConvertLanguageTests = []struct {
+	lang     string
+	input    string
+	expected string
+}{
+	{"pl", "lot ikara", "Lot ikara"},
+	{"pl", "LOT IKARA", "Lot ikara"},
+	{"pl", "Lot Ikara", "Lot Ikara"},
+	{"pl", "Czesław Niemen - Dziwny Jest Ten Świat", "Czesław Niemen - Dziwny Jest Ten Świat"},
+	{"pl", "czesław niemen - dziwny jest ten świat", "Czesław niemen - Dziwny jest ten świat"},
+	{"pl", "niemen (wersja koncertowa)", "Niemen (Wersja koncertowa)"},
+	{"pl", "ballada o iTunes", "Ballada o iTunes"},
+	{"de", "das Lied von der Glocke", "Das Lied von der Glocke"},
+	{"de", "DAS LIED", "Das lied"},
+	{"fr", "la vie en rose", "La Vie en Rose"},
+	{"fr", "chanson d'amour", "Chanson d'Amour"},
+	{"fr", "l'hymne à l'amour", "L'Hymne à l'Amour"},
+	{"fr", "le chant des partisans", "Le Chant des Partisans"},
+	{"fr", "la vie d'un homme", "La Vie d'un Homme"},
+	{"fr", "le temps d'un", "Le Temps d'Un"},
+	{"fr", "chanson - l'amour", "Chanson - L'Amour"},
+	{"en", "la vie en rose", "La Vie en Rose"},
+}
+
+func TestConvertLanguage(t *testing.T) {
+	for i, tt := range ConvertLanguageTests {
+		lang, ok := LookupLanguage(tt.lang)
+		assert.True(t, ok)
+
+		actual := ConvertLanguage(tt.input, lang, nil, nil)
+
+		assert.Equal(t, tt.expected, actual, fmt.Sprintf("%d", i))
+	}
+}
+
+func TestLookupLanguage(t *testing.T) {
+	for _, name := range []string{"pl", "PL", "pol", "pl_PL", "pl-PL", "Polish"} {
+		lang, ok := LookupLanguage(name)
+
+		assert.True(t, ok, name)
+		assert.Equal(t, Languages["pl"], lang, name)
+	}
+
+	_, ok := LookupLanguage("xx")
+	assert.False(t, ok)
+}
+
+var ConverterTests = []struct {
+	opts     Options
+	input    string
+	expected string
+}{
+	{Options{}, "the lord of the rings", "The Lord of the Rings"},
+	{Options{Style: NYT}, "the lord of the rings", "The Lord of the Rings"},
+	{Options{SmallWords: []string{"lord"}}, "the lord of the rings", "The lord Of The Rings"},
+	{Options{SmallWords: []string{}}, "the lord of the rings", "The Lord Of The Rings"},
+	{Options{SmallWords: []string{"feat."}}, "song feat. artist", "Song feat. Artist"},
+	{Options{SmallWords: []string{"feat."}}, "song feat artist", "Song feat Artist"},
+	{Options{SmallWords: []string{"feat."}}, "song fear artist", "Song Fear Artist"},
+	{Options{SmallWords: []string{"(", "a.c"}}, "abc of (live)", "Abc Of (Live)"},
+	{Options{Language: Languages["pl"]}, "władca pierścieni", "Władca pierścieni"},
+	{Options{Exceptions: []string{"deadmau5", "iPhone"}}, "DEADMAU5 ON IPHONE", "deadmau5 on iPhone"},
+	{Options{Exceptions: []string{"iPhone"}}, "my iphone, your iphone", "My iPhone, Your iPhone"},
+}
+
+func TestConverter(t *testing.T) {
+	for i, tt := range ConverterTests {
+		actual := NewConverter(tt.opts).Convert(tt.input)
+
+		assert.Equal(t, tt.expected, actual, fmt.Sprintf("%d", i))
+	}
+}
+
+func TestConverter_Concurrent(t *testing.T) {
+	c := NewConverter(Options{})
+	done := make(chan string)
+	for i := 0; i < 8; i++ {
+		go func() {
+			done <- c.Convert("the lord of the rings")
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		assert.Equal(t, "The Lord of the Rings", <-done)
+	}
+}
+
+var ConverterExceptionsTests = []struct {
+	input    string
+	expected string
+}{
+	{"a-ha live at ac/dc's house", "a-ha Live at AC/DC's House"},
+	{"songs by p!nk feat. deadmau5", "Songs by P!nk feat. deadmau5"},
+	{"the the: infected", "The The: Infected"},
+	{"live with the the", "Live With The The"},
+	{"'guns n' roses'", "'Guns N' Roses'"},
+}
+
+func TestConverter_Exceptions(t *testing.T) {
+	exceptions, err := LoadExceptions(strings.NewReader(`
+# bands
+a-ha
+AC/DC
+P!nk
+deadmau5
+The The
+Guns N' Roses
+
+feat.
+`))
+	assert.NoError(t, err)
+	assert.Len(t, exceptions, 7)
+
+	c := NewConverter(Options{Exceptions: exceptions})
+	for i, tt := range ConverterExceptionsTests {
+		actual := c.Convert(tt.input)
+
+		assert.Equal(t, tt.expected, actual, fmt.Sprintf("%d", i))
+	}
+}
+
+var ConvertUnicodeTests = []struct {
+	input    string
+	expected string
+}{
+	{"ŻÓŁTA ŁÓDŹ", "Żółta Łódź"},
+	{"żółta łódź", "Żółta Łódź"},
+	{"élan vital", "Élan Vital"},
+	{"КИНО: ГРУППА КРОВИ", "Кино: Группа Крови"},
+	{"группа крови", "Группа Крови"},
+	{"ΚΑΛΟΣ ΚΟΣΜΟΣ", "Καλος Κοσμος"},
+	{"o’reilly’s café", "O’Reilly’s Café"},
+	{"d’artagnan", "D’Artagnan"},
+	{"“a day in the life”", "“A Day in the Life”"},
+	{"«où est à»", "«Où Est À»"},
+	{"Ǆemal", "ǅemal"},
+	{"ŁÓDŹ D.C.", "Łódź D.C."},
+}
+
+func TestConvert_Unicode(t *testing.T) {
+	for i, tt := range ConvertUnicodeTests {
+		actual := Convert(tt.input, nil, nil)
+
+		assert.Equal(t, tt.expected, actual, fmt.Sprintf("%d", i))
+	}
+}
+
+var ConverterModeTests = []struct {
+	opts     Options
+	input    string
+	expected string
+}{
+	{Options{Mode: SentenceMode}, "THE LORD OF THE RINGS", "The lord of the rings"},
+	{Options{Mode: SentenceMode}, "the lord of iTunes", "The lord of iTunes"},
+	{Options{Mode: StartMode}, "the lord of the rings", "The Lord Of The Rings"},
+	{Options{Mode: StartMode}, "the lord of iTunes", "The Lord Of iTunes"},
+	{Options{Mode: LowerMode}, "The Lord of the Rings", "the lord of the rings"},
+	{Options{Mode: LowerMode}, "ΚΑΛΟΣ", "καλος"},
+	{Options{Mode: UpperMode}, "The Lord of the Rings", "THE LORD OF THE RINGS"},
+	{Options{Conservative: true}, "the lord of the rings", "The Lord of the Rings"},
+	{Options{Conservative: true}, "THE LORD OF THE RINGS", "The Lord of the Rings"},
+	{Options{Conservative: true}, "The lord of the rings", "The lord of the rings"},
+	{Options{Conservative: true, Mode: UpperMode}, "the lord", "THE LORD"},
+}
+
+func TestConverter_Mode(t *testing.T) {
+	for i, tt := range ConverterModeTests {
+		actual := NewConverter(tt.opts).Convert(tt.input)
+
+		assert.Equal(t, tt.expected, actual, fmt.Sprintf("%d", i))
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for name, expected := range Modes {
+		actual, err := ParseMode(strings.ToUpper(name))
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+		assert.Equal(t, name, actual.String())
+	}
+
+	_, err := ParseMode("bogus")
+	assert.Error(t, err)
+}
+
+func TestParseModeOptions(t *testing.T) {
+	opts, err := ParseModeOptions("Conservative")
+	assert.NoError(t, err)
+	assert.Equal(t, Options{Mode: TitleMode, Conservative: true}, opts)
+
+	opts, err = ParseModeOptions("sentence")
+	assert.NoError(t, err)
+	assert.Equal(t, Options{Mode: SentenceMode}, opts)
+
+	_, err = ParseModeOptions("bogus")
+	assert.Error(t, err)
+}
+
+var ConverterStyleTests = []struct {
+	style    string
+	input    string
+	expected string
+}{
+	{"nyt", "walking through the fire with me", "Walking Through the Fire With Me"},
+	{"chicago", "walking through the fire with me", "Walking through the Fire with Me"},
+	{"chicago", "an up-to-date guide", "An Up-to-Date Guide"},
+	{"ap", "walking through the fire with me", "Walking Through the Fire With Me"},
+	{"ap", "a day in the life", "A Day in the Life"},
+	{"apa", "an up-to-date guide", "An Up-To-Date Guide"},
+	{"music", "give it up for the band", "Give It Up for the Band"},
+	{"music", "turn on the lights", "Turn On the Lights"},
+	{"music", "man on the moon", "Man on the Moon"},
+	{"music", "shakin' up the night", "Shakin' Up the Night"},
+	{"music", "we're getting out of here", "We're Getting Out of Here"},
+	{"music", "stepping into the light", "Stepping Into the Light"},
+}
+
+func TestConverter_Style(t *testing.T) {
+	for i, tt := range ConverterStyleTests {
+		style, ok := LookupStyle(tt.style)
+		assert.True(t, ok)
+
+		actual := NewConverter(Options{Style: style}).Convert(tt.input)
+
+		assert.Equal(t, tt.expected, actual, fmt.Sprintf("%d", i))
+	}
+}
+
+func TestConverter_Hyphenation(t *testing.T) {
+	style := &Style{SmallWords: NYT.SmallWords, Hyphenated: HyphenFirst}
+
+	actual := NewConverter(Options{Style: style}).Convert("an up-to-date guide")
+
+	assert.Equal(t, "An Up-to-date Guide", actual)
+}
+
+var ConverterVersionTests = []struct {
+	opts     Options
+	input    string
+	expected string
+}{
+	{Options{}, "song (live at wembley) [2011 remaster]", "Song (Live at Wembley) [2011 Remaster]"},
+	{Options{}, "walk on the wild side - radio edit", "Walk on the Wild Side - Radio Edit"},
+	{Options{}, "the night (of the living dead)", "The Night (Of the Living Dead)"},
+	{Options{}, "come to me – a remix", "Come to Me – A Remix"},
+	{Options{}, "what is it for (the remix)", "What Is It For (The Remix)"},
+	{Options{}, "song (feat. someone)", "Song (feat. Someone)"},
+	{Options{}, "SONG (FT. SOMEONE)", "Song (ft. Someone)"},
+	{Options{}, "feat. someone", "Feat. Someone"},
+	{Options{Mode: SentenceMode}, "song (live at wembley)", "Song (Live at wembley)"},
+	{Options{VersionKeywords: []string{"RMX"}}, "song (rmx)", "Song (RMX)"},
+	{Options{VersionKeywords: []string{}}, "SONG (FEAT. SOMEONE)", "Song (Feat. Someone)"},
+}
+
+func TestConverter_Version(t *testing.T) {
+	for i, tt := range ConverterVersionTests {
+		actual := NewConverter(tt.opts).Convert(tt.input)
+
+		assert.Equal(t, tt.expected, actual, fmt.Sprintf("%d", i))
+	}
+}
+
+var ConverterAcronymsTests = []struct {
+	opts     Options
+	input    string
+	expected string
+}{
+	{Options{}, "Symphony no. iv", "Symphony No. IV"},
+	{Options{}, "BBC SESSIONS VOL II", "BBC Sessions Vol II"},
+	{Options{}, "the bbc sessions, vol. ii", "The BBC Sessions, Vol. II"},
+	{Options{}, "live at the bbc's studio (dj mix)", "Live at the BBC's Studio (DJ Mix)"},
+	{Options{}, "RICHARD III", "Richard III"},
+	{Options{}, "XX", "Xx"},
+	{Options{}, "CHAPTER XX", "Chapter XX"},
+	{Options{}, "symphony no. xl", "Symphony No. XL"},
+	{Options{}, "part mcmxcix", "Part MCMXCIX"},
+	{Options{}, "Rocky IV", "Rocky IV"},
+	{Options{}, "Henry viii, part ii", "Henry VIII, Part II"},
+	{Options{}, "xi jinping", "Xi Jinping"},
+	{Options{}, "XI JINPING", "Xi Jinping"},
+	{Options{}, "io vi amo", "Io Vi Amo"},
+	{Options{}, "Io Vi Amo", "Io Vi Amo"},
+	{Options{}, "EXTENDED MIX", "Extended Mix"},
+	{Options{}, "Jet Li", "Jet Li"},
+	{Options{}, "this v. that", "This v. That"},
+	{Options{}, "I AM", "I Am"},
+	{Options{Mode: SentenceMode}, "THE BBC SESSIONS VOL. III", "The BBC sessions vol. III"},
+	{Options{Acronyms: []string{"KEXP"}}, "live on kexp (bbc)", "Live on KEXP (Bbc)"},
+}
+
+func TestConverter_Acronyms(t *testing.T) {
+	for i, tt := range ConverterAcronymsTests {
+		actual := NewConverter(tt.opts).Convert(tt.input)
+
+		assert.Equal(t, tt.expected, actual, fmt.Sprintf("%d", i))
+	}
+}
+
+func TestConverter_Explain(t *testing.T) {
+	c := NewConverter(Options{Exceptions: []string{"deadmau5"}})
+
+	actual := c.Explain("a walk in the park: a story of deadmau5 and iTunes in D.C.\nOF")
+
+	expected := []Rule{
+		RuleSmallFirst, RuleCapitalized, RuleSmallWord, RuleSmallWord,
+		RuleCapitalized, RuleSubPhrase, RuleCapitalized, RuleSmallWord,
+		RuleException, RuleSmallWord, RuleMixedCase, RuleSmallWord,
+		RuleInlinePeriod, RuleSmallFirst,
+	}
+	assert.Len(t, actual, len(expected))
+	for i, decision := range actual {
+		assert.Equal(t, expected[i], decision.Rule, decision.String())
+	}
+	assert.Equal(t, Decision{Line: 1, Input: "OF", Output: "Of", Rule: RuleSmallFirst}, actual[13])
+}
+
+func TestConverter_ExplainHooks(t *testing.T) {
+	c := NewConverter(Options{
+		PreHook: func(word string, allCaps bool) (string, bool) {
+			return word, word == "x"
+		},
+		PostHook: func(word string, allCaps bool) string {
+			if word == "Y" {
+				return "why"
+			}
+			return word
+		},
+	})
+
+	actual := c.Explain("x y z")
+
+	assert.Equal(t, RulePreHook, actual[0].Rule)
+	assert.Equal(t, RulePostHook, actual[1].Rule)
+	assert.Equal(t, RuleCapitalized, actual[2].Rule)
+	assert.Equal(t, "why", actual[1].Output)
+}
+
+func TestConverter_ExplainMode(t *testing.T) {
+	actual := NewConverter(Options{Mode: UpperMode}).Explain("a b")
+
+	assert.Equal(t, []Decision{
+		{Input: "a", Output: "A", Rule: RuleMode},
+		{Input: "b", Output: "B", Rule: RuleMode},
+	}, actual)
+}
@@ -0,0 +1,126 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package titlecase
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	openingBrackets = "([{"
+	closingBrackets = ")]}"
+	phraseEnds      = ":;?!"
+	subPhraseStarts = ":.;?!"
+)
+
+var dashes = []string{"-", "--", "–", "—"}
+
+// token is a single whitespace separated word of a line,
+// together with its position in (sub)phrases.
+type token struct {
+	lead, core, trail string
+
+	// dash is a standalone dash, separating sub-phrases.
+	dash bool
+	// first and last word of a (sub)phrase, the line itself,
+	// a bracketed part or a part separated with a dash.
+	first, last bool
+	// subPhrase means first follows a punctuation, like a colon.
+	subPhrase bool
+	// suffix is a bracketed part or a part following a dash,
+	// which usually hold version information, e.g. (Live) or - Radio Edit.
+	suffix bool
+}
+
+// splitPunct splits word into leading punctuation, core and trailing punctuation.
+func splitPunct(word string) (lead, core, trail string) {
+	core = strings.TrimLeft(word, punctChars)
+	lead = word[:len(word)-len(core)]
+	trimmed := strings.TrimRight(core, punctChars)
+	trail = core[len(trimmed):]
+	return lead, trimmed, trail
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+// tokenize splits line into tokens the same way rWords does,
+// empty tokens (from repeated whitespace) included.
+func tokenize(line string) []*token {
+	words := rWords.Split(line, -1)
+	tokens := make([]*token, len(words))
+	var prev *token
+	prevWord := ""
+	depth := 0
+	afterDash := false
+
+	for i, word := range words {
+		tok := &token{}
+		tokens[i] = tok
+		if word == "" {
+			continue
+		}
+		tok.lead, tok.core, tok.trail = splitPunct(word)
+		if contains(dashes, word) {
+			tok.dash = true
+			afterDash = true
+			if prev != nil {
+				prev.last = true
+			}
+			prev, prevWord = tok, word
+			continue
+		}
+
+		opening := strings.ContainsAny(tok.lead, openingBrackets)
+		closing := strings.ContainsAny(tok.trail, closingBrackets)
+		if opening {
+			depth++
+			if prev != nil {
+				prev.last = true
+			}
+		}
+		tok.suffix = depth > 0 || afterDash
+
+		switch {
+		case prev == nil || prev.dash || opening:
+			tok.first = true
+		case strings.ContainsRune(subPhraseStarts, lastRune(prevWord)):
+			tok.first = true
+			tok.subPhrase = true
+		}
+		if closing || strings.ContainsRune(phraseEnds, lastRune(word)) {
+			tok.last = true
+		}
+		if closing && depth > 0 {
+			depth--
+		}
+
+		prev, prevWord = tok, word
+	}
+	if prev != nil {
+		prev.last = true
+	}
+	return tokens
+}
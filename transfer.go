@@ -0,0 +1,320 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/mitchellh/cli"
+)
+
+var audioExts = []string{
+	".aac", ".aif", ".aiff", ".ape", ".flac", ".m4a", ".mp3", ".mp4",
+	".mpc", ".ogg", ".opus", ".wav", ".wma", ".wv",
+}
+
+var rTrackPrefix = regexp.MustCompile(`^[\d\s._-]+`)
+
+// listAudio returns audio files in dir, sorted by name.
+func listAudio(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, info := range infos {
+		ext := strings.ToLower(filepath.Ext(info.Name()))
+		if !info.IsDir() && contains(audioExts, ext) {
+			files = append(files, filepath.Join(dir, info.Name()))
+		}
+	}
+	return files, nil
+}
+
+// normalizeName reduces file name to lower case letters and digits,
+// without the extension and leading track number.
+func normalizeName(file string) string {
+	name := filepath.Base(file)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if trimmed := rTrackPrefix.ReplaceAllString(name, ""); trimmed != "" {
+		name = trimmed
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+func leadingNumber(value string) string {
+	n, err := strconv.Atoi(strings.TrimSpace(strings.SplitN(value, "/", 2)[0]))
+	if err != nil {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// trackKey returns disc and track number of file, e.g. "1/3".
+func trackKey(tags Tags, file string) string {
+	track := leadingNumber(tags.Get(formatKey("tracknumber", "", file)))
+	if track == "" {
+		return ""
+	}
+	disc := leadingNumber(tags.Get("discnumber"))
+	if disc == "" {
+		disc = "1"
+	}
+	return disc + "/" + track
+}
+
+type transferPair struct {
+	src, dst string
+}
+
+// pairByKey pairs files with equal keys. Files with an empty key,
+// or a key shared with other files on the same side, are not paired.
+func pairByKey(oldFiles, newFiles []string, key func(string) string) []transferPair {
+	count := func(files []string) map[string][]string {
+		index := map[string][]string{}
+		for _, file := range files {
+			if k := key(file); k != "" {
+				index[k] = append(index[k], file)
+			}
+		}
+		return index
+	}
+	oldIndex, newIndex := count(oldFiles), count(newFiles)
+
+	var pairs []transferPair
+	for _, file := range oldFiles {
+		k := key(file)
+		if len(oldIndex[k]) == 1 && len(newIndex[k]) == 1 {
+			pairs = append(pairs, transferPair{file, newIndex[k][0]})
+		}
+	}
+	return pairs
+}
+
+// pairByDuration pairs files whose durations (in seconds) differ
+// by at most tolerance, closest ones first.
+func pairByDuration(oldFiles, newFiles []string, duration map[string]int, tolerance int) []transferPair {
+	type candidate struct {
+		i, j, diff int
+	}
+	var candidates []candidate
+	for i, o := range oldFiles {
+		for j, n := range newFiles {
+			do, ok1 := duration[o]
+			dn, ok2 := duration[n]
+			diff := do - dn
+			if diff < 0 {
+				diff = -diff
+			}
+			if ok1 && ok2 && diff <= tolerance {
+				candidates = append(candidates, candidate{i, j, diff})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].diff < candidates[b].diff
+	})
+
+	matched := make([]int, len(oldFiles))
+	usedNew := make([]bool, len(newFiles))
+	for i := range matched {
+		matched[i] = -1
+	}
+	for _, c := range candidates {
+		if matched[c.i] == -1 && !usedNew[c.j] {
+			matched[c.i] = c.j
+			usedNew[c.j] = true
+		}
+	}
+
+	var pairs []transferPair
+	for i, j := range matched {
+		if j != -1 {
+			pairs = append(pairs, transferPair{oldFiles[i], newFiles[j]})
+		}
+	}
+	return pairs
+}
+
+type TransferCommand struct {
+	ui     cli.Ui
+	wg     sync.WaitGroup
+	copier *tagCopier
+	where  *Query
+}
+
+func (cmd *TransferCommand) Process(tags Tags, pair transferPair) {
+	defer cmd.wg.Done()
+
+	if err := cmd.copier.copy(tags, pair.src, pair.dst); err != nil {
+		cmd.ui.Error(fmt.Sprintf("`%s`: %s", pair.dst, err))
+	}
+}
+
+// readAll reads tags of all files, reporting failures.
+func (cmd *TransferCommand) readAll(files []string) map[string]Tags {
+	tags := map[string]Tags{}
+	for _, file := range files {
+		t, err := readTags(file)
+		if err != nil {
+			cmd.ui.Error(fmt.Sprintf("`%s`: %s", file, err))
+			continue
+		}
+		tags[file] = t
+	}
+	return tags
+}
+
+func (cmd *TransferCommand) pair(method string, oldFiles, newFiles []string, oldTags map[string]Tags) ([]transferPair, error) {
+	switch method {
+	case "track":
+		newTags := cmd.readAll(newFiles)
+		return pairByKey(oldFiles, newFiles, func(file string) string {
+			if tags, ok := oldTags[file]; ok {
+				return trackKey(tags, file)
+			}
+			return trackKey(newTags[file], file)
+		}), nil
+	case "name":
+		return pairByKey(oldFiles, newFiles, normalizeName), nil
+	case "duration":
+		duration := map[string]int{}
+		for _, file := range append(append([]string{}, oldFiles...), newFiles...) {
+			props, err := readAudioProps(file)
+			if err != nil {
+				cmd.ui.Error(fmt.Sprintf("`%s`: %s", file, err))
+				continue
+			}
+			if seconds, err := strconv.Atoi(props.Get("_duration")); err == nil {
+				duration[file] = seconds
+			}
+		}
+		return pairByDuration(oldFiles, newFiles, duration, 2), nil
+	}
+	return nil, fmt.Errorf("unknown pairing method `%s`", method)
+}
+
+func (cmd *TransferCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("transfer", flag.ContinueOnError)
+	flags.Usage = func() { cmd.ui.Output(cmd.Help()) }
+	yes := flags.Bool("Y", false, "")
+	method := flags.String("m", "track", "")
+	tagsFlag := flags.String("t", "", "")
+	excludeFlag := flags.String("e", "", "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	args = flags.Args()
+	if len(args) != 2 {
+		cmd.ui.Output(cmd.Help())
+		return 1
+	}
+
+	cmd.copier = &tagCopier{}
+	cmd.copier.parseKeys(*tagsFlag, *excludeFlag)
+
+	oldFiles, err := listAudio(args[0])
+	if err != nil {
+		cmd.ui.Error(err.Error())
+		return 1
+	}
+	newFiles, err := listAudio(args[1])
+	if err != nil {
+		cmd.ui.Error(err.Error())
+		return 1
+	}
+
+	oldFiles = cmd.where.Filter(cmd.ui, oldFiles)
+	oldTags := cmd.readAll(oldFiles)
+	pairs, err := cmd.pair(*method, oldFiles, newFiles, oldTags)
+	if err != nil {
+		cmd.ui.Error(err.Error())
+		return 1
+	}
+
+	paired := map[string]bool{}
+	for _, pair := range pairs {
+		cmd.ui.Output(fmt.Sprintf("`%s` -> `%s`", pair.src, pair.dst))
+		paired[pair.src], paired[pair.dst] = true, true
+	}
+	for _, file := range append(append([]string{}, oldFiles...), newFiles...) {
+		if !paired[file] {
+			cmd.ui.Error(fmt.Sprintf("`%s`: no match found", file))
+		}
+	}
+	if len(pairs) == 0 {
+		return 1
+	}
+
+	if !*yes {
+		answer, err := cmd.ui.Ask(fmt.Sprintf("Transfer tags of %d file(s)? [y/N]", len(pairs)))
+		if err != nil || strings.ToLower(strings.TrimSpace(answer)) != "y" {
+			return 1
+		}
+	}
+
+	for _, pair := range pairs {
+		cmd.wg.Add(1)
+		go cmd.Process(oldTags[pair.src], pair)
+	}
+
+	cmd.wg.Wait()
+	return 0
+}
+
+func (cmd *TransferCommand) Help() string {
+	return strings.TrimSpace(`
+usage: tu transfer [-Y] [-m METHOD] [-t TAGS] [-e TAGS] OLD_DIR NEW_DIR
+
+Copies tags from audio files in OLD_DIR onto their counterparts in NEW_DIR,
+	e.g. after re-ripping an album to a different format.
+Tag names are mapped between formats, e.g. YEAR in MP3 is DATE in FLAC.
+
+-Y Answer Yes to all questions.
+-m METHOD How to pair the files, one of:
+	track (default): by disc and track number tags,
+	name: by file name, ignoring case, punctuation and track number prefix,
+	duration: by length, within 2 seconds (requires ffprobe).
+-t TAGS Comma separated list of tag names to copy.
+	If not specified, copies everything.
+-e TAGS Comma separated list of tag names not to copy,
+	e.g. encoder or replaygain tags, which are specific to the old files.
+	`)
+}
+
+func (cmd *TransferCommand) Synopsis() string {
+	return "Transfers tags between two sets of files"
+}
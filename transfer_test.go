@@ -0,0 +1,100 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListAudio(t *testing.T) {
+	dir := renameDir(t, "01.flac", "02.MP3", "cover.jpg", "rip.log")
+	defer os.RemoveAll(dir)
+
+	files, err := listAudio(dir)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "01.flac"), filepath.Join(dir, "02.MP3"),
+	}, files)
+}
+
+var NormalizeNameTests = []struct {
+	file     string
+	expected string
+}{
+	{"old/01 - So What.mp3", "sowhat"},
+	{"new/1. so what!.flac", "sowhat"},
+	{"Blue in Green.flac", "blueingreen"},
+	{"1999.flac", "1999"},
+	{"02_Żółw.ogg", "żółw"},
+}
+
+func TestNormalizeName(t *testing.T) {
+	for i, tt := range NormalizeNameTests {
+		actual := normalizeName(tt.file)
+
+		assert.Equal(t, tt.expected, actual, fmt.Sprintf("%d", i))
+	}
+}
+
+func TestTrackKey(t *testing.T) {
+	assert.Equal(t, "1/3", trackKey(Tags{{"TRACKNUMBER", "03/09"}}, "a.flac"))
+	assert.Equal(t, "2/3", trackKey(Tags{{"track", "3"}, {"discnumber", "2/2"}}, "a.mp3"))
+	assert.Equal(t, "", trackKey(Tags{{"TRACKNUMBER", "3"}}, "a.mp3"))
+	assert.Equal(t, "", trackKey(Tags{{"TRACKNUMBER", "A1"}}, "a.flac"))
+}
+
+func TestPairByKey(t *testing.T) {
+	keys := map[string]string{
+		"a.mp3": "1", "b.mp3": "2", "c.mp3": "3", "d.mp3": "3", "e.mp3": "",
+		"a.flac": "1", "b.flac": "2", "c.flac": "3", "e.flac": "",
+	}
+	key := func(file string) string { return keys[file] }
+
+	pairs := pairByKey(
+		[]string{"a.mp3", "b.mp3", "c.mp3", "d.mp3", "e.mp3"},
+		[]string{"b.flac", "a.flac", "c.flac", "e.flac"},
+		key,
+	)
+
+	assert.Equal(t, []transferPair{{"a.mp3", "a.flac"}, {"b.mp3", "b.flac"}}, pairs)
+}
+
+func TestPairByDuration(t *testing.T) {
+	duration := map[string]int{
+		"a.mp3": 100, "b.mp3": 101, "c.mp3": 300,
+		"a.flac": 100, "b.flac": 102, "c.flac": 400,
+	}
+
+	pairs := pairByDuration(
+		[]string{"a.mp3", "b.mp3", "c.mp3", "d.mp3"},
+		[]string{"a.flac", "b.flac", "c.flac"},
+		duration, 2,
+	)
+
+	assert.Equal(t, []transferPair{{"a.mp3", "a.flac"}, {"b.mp3", "b.flac"}}, pairs)
+}
@@ -22,12 +22,12 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
@@ -54,16 +54,34 @@ func contains(args []string, arg string) bool {
 	return false
 }
 
-func readTags(file string) ([]map[string]string, error) {
-	tagutil := exec.Command("tagutil", "-F", "json", file)
-	out, err := tagutil.Output()
-	if err != nil {
-		return nil, err
+// containsKey reports whether keys holds key, ignoring case as tag keys do.
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if strings.EqualFold(k, key) {
+			return true
+		}
 	}
+	return false
+}
+
+type listFlag []string
+
+func (f *listFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *listFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
 
-	var tags []map[string]string
-	json.Unmarshal(out, &tags)
-	return tags, nil
+// configPath returns location of a tu configuration file.
+func configPath(name string) string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return filepath.Join(dir, "tu", name)
 }
 
 type PatternPiece struct {
@@ -73,11 +91,16 @@ type PatternPiece struct {
 
 type ParseCommand struct {
 	ui      cli.Ui
+	where   *Query
 	wg      sync.WaitGroup
 	pattern []*PatternPiece
 }
 
-func (cmd *ParseCommand) ParsePattern(in string) (out []*PatternPiece) {
+func (cmd *ParseCommand) ParsePattern(in string) []*PatternPiece {
+	return parsePattern(in)
+}
+
+func parsePattern(in string) (out []*PatternPiece) {
 	current := &PatternPiece{}
 	out = append(out, current)
 	simple := true
@@ -96,7 +119,12 @@ func (cmd *ParseCommand) ParsePattern(in string) (out []*PatternPiece) {
 	for i, char := range in {
 		switch {
 		case char == '%':
-			if name {
+			if name && simple && current.Name == "" {
+				// "%%" stands for a literal "%".
+				name = false
+				current.Sep += "%"
+				lookahead(in[i:])
+			} else if name {
 				if simple {
 					current = &PatternPiece{}
 					out = append(out, current)
@@ -114,6 +142,10 @@ func (cmd *ParseCommand) ParsePattern(in string) (out []*PatternPiece) {
 			lookahead(in[i:])
 		default:
 			if simple && !isalnum(char) {
+				if name && current.Name == "" {
+					// A "%" not starting a name is kept literally.
+					current.Sep += "%"
+				}
 				name = false
 			}
 			if name && len(current.Sep) == 0 {
@@ -124,8 +156,20 @@ func (cmd *ParseCommand) ParsePattern(in string) (out []*PatternPiece) {
 			}
 		}
 	}
+	if name && simple && current.Name == "" {
+		current.Sep += "%"
+	}
 
-	return
+	// Literal "%" may have split the text, join it back.
+	merged := out[:1]
+	for _, piece := range out[1:] {
+		if piece.Name == "" {
+			merged[len(merged)-1].Sep += piece.Sep
+			continue
+		}
+		merged = append(merged, piece)
+	}
+	return merged
 }
 
 func (cmd *ParseCommand) Process(file string) {
@@ -164,7 +208,7 @@ func (cmd *ParseCommand) Run(args []string) int {
 	}
 
 	cmd.pattern = cmd.ParsePattern(args[0])
-	files := args[1:]
+	files := cmd.where.Filter(cmd.ui, args[1:])
 
 	for _, file := range files {
 		cmd.wg.Add(1)
@@ -188,40 +232,52 @@ func (cmd *ParseCommand) Synopsis() string {
 	return "Writes tags by applying filename to a pattern"
 }
 
-type EditCommand struct {
-	ui cli.Ui
-}
-
-func (cmd *EditCommand) Run(args []string) int {
-	if len(args) < 1 {
-		cmd.ui.Output(cmd.Help())
-		return 1
-	}
-
-	args = prepend(args, "edit")
-	tagutil := exec.Command("tagutil", args...)
-	tagutil.Stdin = os.Stdin
-	tagutil.Stdout = os.Stdout
-	tagutil.Stderr = os.Stderr
-	if err := tagutil.Run(); err != nil {
-		cmd.ui.Error(err.Error())
-		return 1
+type TitleCaseCommand struct {
+	ui         cli.Ui
+	wg         sync.WaitGroup
+	converter  *titlecase.Converter
+	converters map[*titlecase.Language]*titlecase.Converter
+	explain    bool
+	all        bool
+	exclude    []string
+	where      *Query
+}
+
+// selected reports whether tag k should be converted. Unless keys
+// are given explicitly, only text tags are, see isTextTag.
+func (cmd *TitleCaseCommand) selected(keys []string, k string, values []string) bool {
+	if containsKey(cmd.exclude, k) {
+		return false
+	}
+	if keys != nil {
+		return containsKey(keys, k)
+	}
+	if cmd.all {
+		return true
+	}
+	for _, v := range values {
+		if isTextTag(k, v) {
+			return true
+		}
 	}
-
-	return 0
-}
-
-func (cmd *EditCommand) Help() string {
-	return "usage: tu e FILES..."
-}
-
-func (cmd *EditCommand) Synopsis() string {
-	return "Edits tags interactively using $EDITOR"
+	return false
 }
 
-type TitleCaseCommand struct {
-	ui cli.Ui
-	wg sync.WaitGroup
+func (cmd *TitleCaseCommand) Explain(file string, tags Tags, keys []string, converter *titlecase.Converter) {
+	lines := []string{fmt.Sprintf("`%s`:", file)}
+	for _, k := range tags.Keys() {
+		values := tags.Values(k)
+		if !cmd.selected(keys, k, values) {
+			continue
+		}
+		for _, v := range values {
+			lines = append(lines, fmt.Sprintf("\t%s: %q -> %q", k, v, converter.Convert(v)))
+			for _, decision := range converter.Explain(v) {
+				lines = append(lines, fmt.Sprintf("\t\t%s", decision))
+			}
+		}
+	}
+	cmd.ui.Output(strings.Join(lines, "\n"))
 }
 
 func (cmd *TitleCaseCommand) Process(file string, tags []string) {
@@ -234,174 +290,157 @@ func (cmd *TitleCaseCommand) Process(file string, tags []string) {
 		return
 	}
 
-	var wg sync.WaitGroup
-	ch := make(chan string)
-	for _, tag := range intags {
-		wg.Add(1)
-		go func(tag map[string]string, ch chan string) {
-			defer wg.Done()
+	converter := cmd.converter
+	if converter == nil {
+		converter = cmd.converters[titlecase.Languages["en"]]
+		if lang, ok := titlecase.LookupLanguage(intags.Get("language")); ok {
+			converter = cmd.converters[lang]
+		}
+	}
 
-			for k, v := range tag {
-				if tags == nil || contains(tags, k) {
-					ch <- fmt.Sprintf("set:%s=%s", k, titlecase.Convert(
-						v, nil, nil,
-					))
-				}
-			}
-		}(tag, ch)
+	if cmd.explain {
+		cmd.Explain(file, intags, tags, converter)
+		return
 	}
-	go func() {
-		wg.Wait()
-		close(ch)
-	}()
 
-	var outtags []string
-	for tag := range ch {
-		outtags = append(outtags, tag)
+	var actions []string
+	for _, k := range intags.Keys() {
+		values := intags.Values(k)
+		if !cmd.selected(tags, k, values) {
+			continue
+		}
+		changed := false
+		outvalues := make([]string, len(values))
+		for i, v := range values {
+			outvalues[i] = converter.Convert(v)
+			changed = changed || outvalues[i] != v
+		}
+		if changed {
+			actions = append(actions, tagActions(k, outvalues)...)
+		}
+	}
+	if len(actions) == 0 {
+		return
 	}
 
-	outtags = append(outtags, file)
-	tagutil := exec.Command("tagutil", outtags...)
-	if err := tagutil.Run(); err != nil {
+	if err := writeTags(file, actions); err != nil {
 		cmd.ui.Error(err.Error())
 	}
 }
 
 func (cmd *TitleCaseCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("titlecase", flag.ContinueOnError)
+	flags.Usage = func() { cmd.ui.Output(cmd.Help()) }
+	tagsFlag := flags.String("t", "", "")
+	langFlag := flags.String("lang", "en", "")
+	exceptionsFlag := flags.String("x", configPath("exceptions"), "")
+	modeFlag := flags.String("m", "title", "")
+	styleFlag := flags.String("style", "", "")
+	excludeFlag := flags.String("e", "", "")
+	flags.BoolVar(&cmd.explain, "explain", false, "")
+	flags.BoolVar(&cmd.all, "all", false, "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	args = flags.Args()
 	if len(args) < 1 {
 		cmd.ui.Output(cmd.Help())
 		return 1
 	}
 
 	var tags []string
-	if args[0] == "-t" {
-		if len(args) < 3 {
-			cmd.ui.Output(cmd.Help())
-			return 1
-		}
-		tags = strings.Split(args[1], ",")
-		args = args[2:]
+	if *tagsFlag != "" {
+		tags = strings.Split(*tagsFlag, ",")
 	}
-
-	for _, file := range args {
-		cmd.wg.Add(1)
-		go cmd.Process(file, tags)
+	if *excludeFlag != "" {
+		cmd.exclude = strings.Split(*excludeFlag, ",")
 	}
 
-	cmd.wg.Wait()
-	return 0
-}
-
-func (cmd *TitleCaseCommand) Help() string {
-	return strings.TrimSpace(`
-usage: tu t [-t TAGS] FILES...
-
--t TAGS	Comma separated list of tag names.
-	If not specified, uses everything.
-	`)
-}
-
-func (cmd *TitleCaseCommand) Synopsis() string {
-	return "Title Cases the Tags"
-}
-
-type RenameCommand struct {
-	ui cli.Ui
-}
-
-func (cmd *RenameCommand) Run(args []string) int {
-	if len(args) < 2 {
-		cmd.ui.Output(cmd.Help())
+	opts, err := titlecase.ParseModeOptions(*modeFlag)
+	if err != nil {
+		cmd.ui.Error(err.Error())
 		return 1
 	}
 
-	if args[0] == "-Y" {
-		args[1] = fmt.Sprintf("rename:%s", args[1])
-	} else {
-		args[0] = fmt.Sprintf("rename:%s", args[0])
+	if *styleFlag != "" {
+		style, ok := titlecase.LookupStyle(*styleFlag)
+		if !ok {
+			cmd.ui.Error(fmt.Sprintf("Unknown style `%s`", *styleFlag))
+			return 1
+		}
+		opts.Style = style
 	}
-	args = prepend(args, "-p")
 
-	tagutil := exec.Command("tagutil", args...)
-	if err := tagutil.Run(); err != nil {
+	if f, err := os.Open(*exceptionsFlag); err == nil {
+		opts.Exceptions, err = titlecase.LoadExceptions(f)
+		f.Close()
+		if err != nil {
+			cmd.ui.Error(err.Error())
+			return 1
+		}
+	} else if !os.IsNotExist(err) {
 		cmd.ui.Error(err.Error())
 		return 1
 	}
 
-	return 0
-}
-
-func (cmd *RenameCommand) Help() string {
-	return strings.TrimSpace(`
-usage: tu r [-Y] PATTERN FILES...
-
--Y Answer Yes to all questions.
-
-PATTERN is a string with placeholders in form of %{<name>}
-	or just %<name>, if <name> is one word.
-	`)
-}
-
-func (cmd *RenameCommand) Synopsis() string {
-	return "Renames files by applying tags to a pattern"
-}
-
-type SetCommand struct {
-	ui cli.Ui
-}
-
-func (cmd *SetCommand) Run(args []string) int {
-	sets := []string{}
-	files := []string{}
-
-	var key string
-	infiles := false
-	for _, arg := range args {
-		if arg == "--" {
-			infiles = true
-			continue
-		}
-
-		if infiles {
-			files = append(files, arg)
-			continue
+	if *langFlag == "auto" {
+		cmd.converters = map[*titlecase.Language]*titlecase.Converter{}
+		for _, lang := range titlecase.Languages {
+			opts.Language = lang
+			cmd.converters[lang] = titlecase.NewConverter(opts)
 		}
-
-		if key == "" {
-			key = arg
-		} else {
-			sets = append(sets, fmt.Sprintf("set:%s=%s", key, arg))
-			key = ""
+	} else {
+		lang, ok := titlecase.LookupLanguage(*langFlag)
+		if !ok {
+			cmd.ui.Error(fmt.Sprintf("Unknown language `%s`", *langFlag))
+			return 1
 		}
+		opts.Language = lang
+		cmd.converter = titlecase.NewConverter(opts)
 	}
 
-	if len(sets) == 0 || len(files) == 0 {
-		cmd.ui.Output(cmd.Help())
-		return 1
-	}
-
-	tagutil := exec.Command("tagutil", append(sets, files...)...)
-	if out, err := tagutil.CombinedOutput(); err != nil {
-		cmd.ui.Error(string(out))
-		return 1
+	for _, file := range cmd.where.Filter(cmd.ui, args) {
+		cmd.wg.Add(1)
+		go cmd.Process(file, tags)
 	}
 
+	cmd.wg.Wait()
 	return 0
 }
 
-func (cmd *SetCommand) Help() string {
+func (cmd *TitleCaseCommand) Help() string {
 	return strings.TrimSpace(`
-usage: tu s <TAG VALUE>... -- FILES...
+usage: tu t [-t TAGS | --all] [-e TAGS] [-m MODE] [--style STYLE]
+	[--lang LANG] [-x FILE] [--explain] FILES...
+
+-t TAGS	Comma separated list of tag names.
+	If not specified, uses all text tags, i.e. skips identifiers,
+	numbers, dates, URLs, etc. (MusicBrainz IDs, ISRC, ReplayGain...).
+--all	Use all tags, text or not.
+-e TAGS	Comma separated list of tag names to leave alone.
+-m MODE	One of title (default), sentence, start (every word capitalized),
+	lower, upper or conservative (title, but only for values which are
+	entirely lower or upper case).
+--style STYLE	Title case style guide, one of nyt (default), chicago, ap,
+	apa or music.
+--lang LANG	Language rules to use, one of en (default), pl, de, fr.
+	If 'auto', uses each file's LANGUAGE tag, falling back to en.
+-x FILE	File with exceptions, i.e. words and phrases (one per line)
+	always written exactly as given.
+	Defaults to $XDG_CONFIG_HOME/tu/exceptions.
+--explain	Do not change anything, print which rule decided
+	about every word of every tag value instead.
 	`)
 }
 
-func (cmd *SetCommand) Synopsis() string {
-	return "Sets tags to values in files"
+func (cmd *TitleCaseCommand) Synopsis() string {
+	return "Title Cases the Tags"
 }
 
 type PurgeCommand struct {
-	ui cli.Ui
-	wg sync.WaitGroup
+	ui    cli.Ui
+	wg    sync.WaitGroup
+	where *Query
 }
 
 func (cmd *PurgeCommand) Process(file string, keys []string) {
@@ -416,17 +455,16 @@ func (cmd *PurgeCommand) Process(file string, keys []string) {
 	}
 
 	clears := []string{}
-	for _, tag := range tags {
-		for k := range tag {
-			if !contains(keys, k) {
-				clears = append(clears, fmt.Sprintf("clear:%s", k))
-			}
+	for _, k := range tags.Keys() {
+		if !contains(keys, k) {
+			clears = append(clears, fmt.Sprintf("clear:%s", k))
 		}
 	}
+	if len(clears) == 0 {
+		return
+	}
 
-	clears = append(clears, file)
-	tagutil := exec.Command("tagutil", clears...)
-	if err := tagutil.Run(); err != nil {
+	if err := writeTags(file, clears); err != nil {
 		cmd.ui.Error(err.Error())
 	}
 }
@@ -463,6 +501,9 @@ func (cmd *PurgeCommand) Run(args []string) int {
 		cmd.ui.Output(cmd.Help())
 		return 1
 	}
+	if files = cmd.where.Filter(cmd.ui, files); len(files) == 0 {
+		return 0
+	}
 
 	if !reversed {
 		for i := range keys {
@@ -507,6 +548,7 @@ type NumberCommand struct {
 	format  string
 	total   int
 	letters []byte
+	where   *Query
 }
 
 func (cmd *NumberCommand) Process(file string, no int) {
@@ -557,7 +599,7 @@ func (cmd *NumberCommand) Run(args []string) int {
 		return fmt.Sprintf("%%0%dd", len(s))
 	})
 
-	for _, file := range args[1:] {
+	for _, file := range cmd.where.Filter(cmd.ui, args[1:]) {
 		cmd.wg.Add(1)
 		go cmd.Process(file, *no)
 		*no += 1
@@ -588,34 +630,57 @@ func (cmd *NumberCommand) Synopsis() string {
 
 func main() {
 	ui := &cli.ConcurrentUi{Ui: &cli.BasicUi{Writer: os.Stdout}}
+	args, where, err := extractWhere(os.Args[1:])
+	if err != nil {
+		ui.Error(err.Error())
+		os.Exit(1)
+	}
+
 	commands := map[string]cli.CommandFactory{
 		"w": func() (cli.Command, error) {
-			return &ParseCommand{ui: ui}, nil
+			return &ParseCommand{ui: ui, where: where}, nil
 		},
 		"e": func() (cli.Command, error) {
-			return &EditCommand{ui: ui}, nil
+			return &EditCommand{ui: ui, where: where}, nil
 		},
 		"t": func() (cli.Command, error) {
-			return &TitleCaseCommand{ui: ui}, nil
+			return &TitleCaseCommand{ui: ui, where: where}, nil
 		},
 		"r": func() (cli.Command, error) {
-			return &RenameCommand{ui: ui}, nil
+			return &RenameCommand{ui: ui, where: where}, nil
 		},
 		"s": func() (cli.Command, error) {
-			return &SetCommand{ui: ui}, nil
+			return &SetCommand{ui: ui, where: where}, nil
+		},
+		"cp": func() (cli.Command, error) {
+			return &CopyCommand{ui: ui, where: where}, nil
+		},
+		"transfer": func() (cli.Command, error) {
+			return &TransferCommand{ui: ui, where: where}, nil
+		},
+		"x": func() (cli.Command, error) {
+			return &ExportCommand{ui: ui, where: where}, nil
+		},
+		"i": func() (cli.Command, error) {
+			return &ImportCommand{ui: ui, where: where}, nil
+		},
+		"ls": func() (cli.Command, error) {
+			return &ListCommand{ui: ui, where: where}, nil
 		},
 		"p": func() (cli.Command, error) {
-			return &PurgeCommand{ui: ui}, nil
+			return &PurgeCommand{ui: ui, where: where}, nil
 		},
 		"n": func() (cli.Command, error) {
-			return &NumberCommand{ui: ui}, nil
+			return &NumberCommand{ui: ui, where: where}, nil
 		},
 	}
 
 	cli := &cli.CLI{
-		Args:     os.Args[1:],
+		Args:     args,
 		Commands: commands,
-		HelpFunc: cli.BasicHelpFunc("tu"),
+		HelpFunc: func(commands map[string]cli.CommandFactory) string {
+			return cli.BasicHelpFunc("tu")(commands) + whereHelp
+		},
 	}
 
 	exitCode, err := cli.Run()
@@ -79,6 +79,17 @@ var ParsePatternTests = []struct {
 	{"%{n%_1b}_", []*PatternPiece{
 		{Sep: "_", Name: "n%_1b"},
 	}},
+	{"50% of %{artist}", []*PatternPiece{
+		{Sep: "50% of "},
+		{Name: "artist"},
+	}},
+	{"%{n1}%%%n2%", []*PatternPiece{
+		{Sep: "%", Name: "n1"},
+		{Sep: "%", Name: "n2"},
+	}},
+	{"%%%%", []*PatternPiece{
+		{Sep: "%%"},
+	}},
 	{"%{artist}_-_%album - %{tracknumber}@%title.flac", []*PatternPiece{
 		{Sep: "_-_", Name: "artist"},
 		{Sep: " - ", Name: "album"},
@@ -99,3 +110,26 @@ func TestParsePattern(t *testing.T) {
 		)
 	}
 }
+
+var TitleCaseSelectedTests = []struct {
+	keys     []string
+	exclude  []string
+	key      string
+	expected bool
+}{
+	{nil, []string{"title"}, "TITLE", false},
+	{nil, []string{"TITLE"}, "title", false},
+	{nil, []string{"title"}, "ALBUM", true},
+	{[]string{"title"}, nil, "TITLE", true},
+	{[]string{"title"}, nil, "ALBUM", false},
+	{[]string{"title", "album"}, []string{"Album"}, "ALBUM", false},
+}
+
+func TestTitleCaseCommand_Selected(t *testing.T) {
+	for i, tt := range TitleCaseSelectedTests {
+		cmd := &TitleCaseCommand{exclude: tt.exclude}
+		actual := cmd.selected(tt.keys, tt.key, []string{"some words"})
+
+		assert.Equal(t, tt.expected, actual, fmt.Sprintf("%d", i))
+	}
+}
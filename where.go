@@ -0,0 +1,423 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/mitchellh/cli"
+)
+
+// queryNode is a single node of a parsed --where expression.
+type queryNode interface {
+	match(tags Tags) bool
+	names() []string
+}
+
+type andNode struct {
+	left, right queryNode
+}
+
+func (n *andNode) match(tags Tags) bool {
+	return n.left.match(tags) && n.right.match(tags)
+}
+
+func (n *andNode) names() []string {
+	return append(n.left.names(), n.right.names()...)
+}
+
+type orNode struct {
+	left, right queryNode
+}
+
+func (n *orNode) match(tags Tags) bool {
+	return n.left.match(tags) || n.right.match(tags)
+}
+
+func (n *orNode) names() []string {
+	return append(n.left.names(), n.right.names()...)
+}
+
+type notNode struct {
+	node queryNode
+}
+
+func (n *notNode) match(tags Tags) bool {
+	return !n.node.match(tags)
+}
+
+func (n *notNode) names() []string {
+	return n.node.names()
+}
+
+type hasNode struct {
+	key string
+}
+
+func (n *hasNode) match(tags Tags) bool {
+	for _, value := range tags.Values(n.key) {
+		if value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *hasNode) names() []string {
+	return []string{n.key}
+}
+
+// cmpNode compares values of a tag with a value. It matches if any
+// of the values does. Missing tag matches nothing. Negated operators
+// (!= and !~) wrap it in a notNode, so they match a missing tag.
+type cmpNode struct {
+	key   string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+func (n *cmpNode) test(value string) bool {
+	switch n.op {
+	case "=":
+		return strings.EqualFold(value, n.value)
+	case "~":
+		return n.re.MatchString(value)
+	case "<":
+		return compareValues(value, n.value) < 0
+	case "<=":
+		return compareValues(value, n.value) <= 0
+	case ">":
+		return compareValues(value, n.value) > 0
+	case ">=":
+		return compareValues(value, n.value) >= 0
+	}
+	return false
+}
+
+func (n *cmpNode) match(tags Tags) bool {
+	for _, value := range tags.Values(n.key) {
+		if n.test(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *cmpNode) names() []string {
+	return []string{n.key}
+}
+
+var queryOps = []string{"!=", "!~", "<=", ">=", "=", "~", "<", ">"}
+
+type queryToken struct {
+	text   string
+	quoted bool
+	pos    int
+}
+
+func tokenizeQuery(in string) ([]queryToken, error) {
+	var tokens []queryToken
+	for i := 0; i < len(in); {
+		c := in[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, queryToken{text: string(c), pos: i})
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(in[i+1:], c)
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated string at %d", i+1)
+			}
+			tokens = append(tokens, queryToken{text: in[i+1 : i+1+end], quoted: true, pos: i})
+			i += end + 2
+		default:
+			op := ""
+			for _, o := range queryOps {
+				if strings.HasPrefix(in[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op != "" {
+				tokens = append(tokens, queryToken{text: op, pos: i})
+				i += len(op)
+				continue
+			}
+			end := strings.IndexFunc(in[i:], func(r rune) bool {
+				return unicode.IsSpace(r) || strings.ContainsRune("()\"'=!~<>", r)
+			})
+			if end == -1 {
+				end = len(in) - i
+			}
+			tokens = append(tokens, queryToken{text: in[i : i+end], pos: i})
+			i += end
+		}
+	}
+	return tokens, nil
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() *queryToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+// keyword reports whether the next token is an unquoted word.
+func (p *queryParser) keyword(word string) bool {
+	t := p.peek()
+	return t != nil && !t.quoted && strings.EqualFold(t.text, word)
+}
+
+func (p *queryParser) unexpected() error {
+	if t := p.peek(); t != nil {
+		return fmt.Errorf("unexpected `%s` at %d", t.text, t.pos+1)
+	}
+	return fmt.Errorf("unexpected end of expression")
+}
+
+func (p *queryParser) word() (string, error) {
+	t := p.peek()
+	if t == nil || (!t.quoted && (contains(queryOps, t.text) || t.text == "(" || t.text == ")")) {
+		return "", p.unexpected()
+	}
+	p.pos++
+	return t.text, nil
+}
+
+func (p *queryParser) or() (queryNode, error) {
+	left, err := p.and()
+	for err == nil && p.keyword("or") {
+		p.pos++
+		var right queryNode
+		if right, err = p.and(); err == nil {
+			left = &orNode{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *queryParser) and() (queryNode, error) {
+	left, err := p.unary()
+	for err == nil && p.keyword("and") {
+		p.pos++
+		var right queryNode
+		if right, err = p.unary(); err == nil {
+			left = &andNode{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *queryParser) unary() (queryNode, error) {
+	if p.keyword("not") {
+		p.pos++
+		node, err := p.unary()
+		return &notNode{node}, err
+	}
+	if p.keyword("(") {
+		p.pos++
+		node, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword(")") {
+			return nil, p.unexpected()
+		}
+		p.pos++
+		return node, nil
+	}
+
+	key, err := p.word()
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(key, "has") && p.keyword("(") {
+		p.pos++
+		if key, err = p.word(); err != nil {
+			return nil, err
+		}
+		if !p.keyword(")") {
+			return nil, p.unexpected()
+		}
+		p.pos++
+		return &hasNode{key}, nil
+	}
+
+	t := p.peek()
+	if t == nil || t.quoted || !contains(queryOps, t.text) {
+		return nil, p.unexpected()
+	}
+	p.pos++
+	value, err := p.word()
+	if err != nil {
+		return nil, err
+	}
+
+	op := strings.TrimPrefix(t.text, "!")
+	node := queryNode(&cmpNode{key: key, op: op, value: value})
+	if op == "~" {
+		re, err := regexp.Compile("(?i)" + value)
+		if err != nil {
+			return nil, err
+		}
+		node.(*cmpNode).re = re
+	}
+	if strings.HasPrefix(t.text, "!") {
+		node = &notNode{node}
+	}
+	return node, nil
+}
+
+// Query is a parsed --where expression, selecting files by their tags.
+type Query struct {
+	root  queryNode
+	audio bool
+}
+
+func ParseQuery(in string) (*Query, error) {
+	tokens, err := tokenizeQuery(in)
+	if err != nil {
+		return nil, fmt.Errorf("where: %s", err)
+	}
+	p := &queryParser{tokens: tokens}
+	root, err := p.or()
+	if err == nil && p.peek() != nil {
+		err = p.unexpected()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("where: %s", err)
+	}
+
+	q := &Query{root: root}
+	for _, name := range root.names() {
+		q.audio = q.audio || contains(audioProps, strings.ToLower(name))
+	}
+	return q, nil
+}
+
+// Match reports whether tags (including file properties) satisfy q.
+func (q *Query) Match(tags Tags) bool {
+	return q.root.match(tags)
+}
+
+// matchFile reads tags and properties of file and matches them against q.
+func (q *Query) matchFile(file string) (bool, error) {
+	tags, err := readTags(file)
+	if err != nil {
+		return false, err
+	}
+	props := append(tags, fileProps(file)...)
+	if q.audio {
+		more, err := readAudioProps(file)
+		if err != nil {
+			return false, err
+		}
+		props = append(props, more...)
+	}
+	return q.Match(props), nil
+}
+
+// Filter returns files matching q, in order, reporting those
+// which could not be read. A nil Query matches everything.
+func (q *Query) Filter(ui cli.Ui, files []string) []string {
+	if q == nil {
+		return files
+	}
+
+	matched := make([]bool, len(files))
+	var wg sync.WaitGroup
+	for i, file := range files {
+		wg.Add(1)
+		go func(i int, file string) {
+			defer wg.Done()
+			ok, err := q.matchFile(file)
+			if err != nil {
+				ui.Error(fmt.Sprintf("`%s`: %s", file, err))
+			}
+			matched[i] = ok
+		}(i, file)
+	}
+	wg.Wait()
+
+	var out []string
+	for i, file := range files {
+		if matched[i] {
+			out = append(out, file)
+		}
+	}
+	return out
+}
+
+const whereHelp = `
+
+Every command accepts --where EXPR, to process only files matching EXPR,
+e.g. 'artist ~ beatles and date < 1970 and not has(albumartist)'.
+Operators are = != (case insensitive), ~ !~ (regexp), < <= > >=
+(numeric, when possible), has(TAG), and, or, not and parentheses.
+A missing tag matches nothing, except for != and !~, which hold when
+no value matches.
+`
+
+// extractWhere removes `--where EXPR` (or `--where=EXPR`) from command
+// arguments, so that every command accepts it. Only arguments before
+// `--` are looked at.
+func extractWhere(args []string) ([]string, *Query, error) {
+	var out []string
+	var where *string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			out = append(out, args[i:]...)
+			i = len(args)
+		case arg == "--where" || arg == "-where":
+			if i+1 == len(args) {
+				return nil, nil, fmt.Errorf("where: missing expression")
+			}
+			i++
+			where = &args[i]
+		case strings.HasPrefix(arg, "--where="):
+			expr := arg[len("--where="):]
+			where = &expr
+		default:
+			out = append(out, arg)
+		}
+	}
+	if where == nil {
+		return out, nil, nil
+	}
+	q, err := ParseQuery(*where)
+	return out, q, err
+}
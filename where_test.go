@@ -0,0 +1,126 @@
+// tu
+// Copyright (C) 2014 Karol 'Kenji Takahashi' Woźniak
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the "Software"),
+// to deal in the Software without restriction, including without limitation
+// the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the
+// Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included
+// in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES
+// OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+// IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+// TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE
+// OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var whereTestTags = Tags{
+	{"ARTIST", "The Beatles"}, {"DATE", "1969"}, {"TRACKNUMBER", "9/17"},
+	{"GENRE", "Rock"}, {"GENRE", "Pop"}, {"COMMENT", ""},
+}
+
+var QueryMatchTests = []struct {
+	expr     string
+	expected bool
+}{
+	{`artist ~ "beatles"`, true},
+	{`artist ~ ^beatles`, false},
+	{`artist !~ stones`, true},
+	{`artist = "the beatles"`, true},
+	{`artist = beatles`, false},
+	{`artist != 'The Beatles'`, false},
+	{`date < 1970`, true},
+	{`date >= 1970`, false},
+	{`tracknumber > 10`, false},
+	{`tracknumber <= 9`, true},
+	{`genre = pop`, true},
+	{`genre != jazz`, true},
+	{`has(artist)`, true},
+	{`has(albumartist)`, false},
+	{`has(comment)`, false},
+	{`albumartist = x`, false},
+	{`albumartist != x`, true},
+	{`albumartist !~ x`, true},
+	{`albumartist < 10`, false},
+	{`artist ~ "beatles" and date < 1970 and not has(albumartist)`, true},
+	{`genre = jazz or genre = rock`, true},
+	{`genre = jazz or genre = blues and date < 1970`, false},
+	{`(genre = jazz or genre = rock) and date<1970`, true},
+	{`NOT (genre = jazz OR genre = rock)`, false},
+	{`_ext = flac`, true},
+	{`_dirname = abbey`, false},
+}
+
+func TestQuery_Match(t *testing.T) {
+	tags := append(whereTestTags, fileProps("01.flac")...)
+	for i, tt := range QueryMatchTests {
+		q, err := ParseQuery(tt.expr)
+		if !assert.NoError(t, err, fmt.Sprintf("%d", i)) {
+			continue
+		}
+
+		assert.Equal(t, tt.expected, q.Match(tags), fmt.Sprintf("%d", i))
+	}
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+	for _, expr := range []string{
+		``, `artist`, `artist =`, `artist = "beatles`, `(artist = x`,
+		`artist = x and`, `has(artist`, `artist ~ "("`, `= x`, `artist = x y`,
+	} {
+		_, err := ParseQuery(expr)
+
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestParseQuery_Audio(t *testing.T) {
+	q, _ := ParseQuery(`artist = x`)
+	assert.False(t, q.audio)
+
+	q, _ = ParseQuery(`_duration > 300`)
+	assert.True(t, q.audio)
+}
+
+func TestQuery_FilterNil(t *testing.T) {
+	var q *Query
+
+	assert.Equal(t, []string{"a", "b"}, q.Filter(nil, []string{"a", "b"}))
+}
+
+func TestExtractWhere(t *testing.T) {
+	args, q, err := extractWhere([]string{"t", "--where", "date < 1970", "-t", "title", "a.flac"})
+	assert.NoError(t, err)
+	assert.NotNil(t, q)
+	assert.Equal(t, []string{"t", "-t", "title", "a.flac"}, args)
+
+	args, q, err = extractWhere([]string{"s", "--where=has(date)", "title", "x", "--", "--where"})
+	assert.NoError(t, err)
+	assert.NotNil(t, q)
+	assert.Equal(t, []string{"s", "title", "x", "--", "--where"}, args)
+
+	args, q, err = extractWhere([]string{"ls", "a.flac"})
+	assert.NoError(t, err)
+	assert.Nil(t, q)
+	assert.Equal(t, []string{"ls", "a.flac"}, args)
+
+	_, _, err = extractWhere([]string{"ls", "--where"})
+	assert.Error(t, err)
+
+	_, _, err = extractWhere([]string{"ls", "--where", "artist ="})
+	assert.Error(t, err)
+}